@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"kasir-api/models"
 	"kasir-api/services"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -29,24 +33,71 @@ func (h *ProductHandler) HandleProducts(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// GetAll - GET /api/produk?name=&category_id=&outlet_id=&min_price=&max_price=&in_stock=true&include_archived=true&sort=-price&page=1&limit=20
 func (h *ProductHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	// Check for search query parameter
-	name := r.URL.Query().Get("name")
-	
-	var products []models.Product
-	var err error
-	
-	if name != "" {
-		products, err = h.service.SearchByName(name)
-	} else {
-		products, err = h.service.GetAll()
-	}
-	
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := models.ProductFilter{
+		Name:  r.URL.Query().Get("name"),
+		Sort:  r.URL.Query().Get("sort"),
+		Page:  page,
+		Limit: limit,
+	}
+
+	if filter.Sort != "" {
+		if _, ok := models.ProductSortColumns[strings.TrimPrefix(filter.Sort, "-")]; !ok {
+			http.Error(w, "sort must be one of: id, name, price, stock (prefix with - for descending)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	categoryID, err := parseOptionalInt(r, "category_id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if categoryID != nil {
+		filter.CategoryID = *categoryID
+	}
+
+	if filter.OutletID, err = parseOutletID(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if filter.MinPrice, err = parseOptionalInt(r, "min_price"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.MaxPrice, err = parseOptionalInt(r, "max_price"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if filter.IncludeArchived, err = parseIncludeArchived(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if v := r.URL.Query().Get("in_stock"); v != "" {
+		filter.InStock, err = strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "in_stock must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	products, err := h.service.GetAll(filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("X-Total-Count", strconv.Itoa(products.Pagination.Total))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(products)
 }
@@ -59,7 +110,16 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateProduct(&product); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	err = h.service.Create(&product)
+	if errors.Is(err, models.ErrDuplicateSKU) || errors.Is(err, models.ErrDuplicateBarcode) || errors.Is(err, models.ErrDuplicatePLU) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -71,7 +131,66 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleProductByID - GET/PUT/DELETE /api/produk/{id}
+// GET /api/produk/barcode/{code}
+// GET /api/produk/low-stock
+// POST /api/produk/{id}/restore
+// POST /api/produk/{id}/stock-adjustments
+// GET /api/produk/{id}/stock-movements
 func (h *ProductHandler) HandleProductByID(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/produk/low-stock" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.GetLowStock(w, r)
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/stock-adjustments") {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.AdjustStock(w, r)
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/stock-movements") {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.GetStockMovements(w, r)
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/batches") {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.GetBatches(w, r)
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/restore") {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.Restore(w, r)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/api/produk/barcode/") {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.GetByBarcode(w, r)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		h.GetByID(w, r)
@@ -103,6 +222,157 @@ func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(product)
 }
 
+// GetLowStock - GET /api/produk/low-stock?category_id=&outlet_id=&page=1&limit=20
+// Produk aktif dengan stock <= min_stock, yang paling sedikit stoknya duluan. Dengan outlet_id stok outlet itu
+// yang dibandingkan, jadi outlet yang kosong tetap muncul walaupun outlet lain masih punya stok.
+func (h *ProductHandler) GetLowStock(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := models.ProductFilter{
+		LowStock: true,
+		Sort:     "stock",
+		Page:     page,
+		Limit:    limit,
+	}
+
+	categoryID, err := parseOptionalInt(r, "category_id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if categoryID != nil {
+		filter.CategoryID = *categoryID
+	}
+
+	if filter.OutletID, err = parseOutletID(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.GetAll(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(result.Pagination.Total))
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleReorderReport - GET /api/report/reorder?days=30&cover_days=14&outlet_id=1
+func (h *ProductHandler) HandleReorderReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days, coverDays := 30, 14
+	params := []struct {
+		key   string
+		value *int
+	}{{"days", &days}, {"cover_days", &coverDays}}
+	for _, param := range params {
+		key, value := param.key, param.value
+		n, err := parseOptionalInt(r, key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if n == nil {
+			continue
+		}
+		if *n < 1 || *n > 365 {
+			http.Error(w, key+" must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		*value = *n
+	}
+
+	outletID, err := parseOutletID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetReorderReport(days, coverDays, outletID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleExpiringReport - GET /api/report/expiring-batches?days=30&outlet_id=1
+func (h *ProductHandler) HandleExpiringReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := 30
+	n, err := parseOptionalInt(r, "days")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if n != nil {
+		if *n < 0 || *n > 365 {
+			http.Error(w, "days must be between 0 and 365", http.StatusBadRequest)
+			return
+		}
+		days = *n
+	}
+
+	outletID, err := parseOutletID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetExpiringBatches(days, outletID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetByBarcode - GET /api/produk/barcode/{code}, dipakai untuk scan-to-cart.
+// Label timbangan (prefix 20-29) juga mengembalikan scanned_quantity dan scanned_subtotal.
+func (h *ProductHandler) GetByBarcode(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimPrefix(r.URL.Path, "/api/produk/barcode/")
+	if code == "" {
+		http.Error(w, "Barcode is required", http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.service.GetByBarcode(code)
+	if errors.Is(err, models.ErrProductNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrInvalidScaleLabel) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
 func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/produk/")
 	id, err := strconv.Atoi(idStr)
@@ -111,15 +381,42 @@ func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var product models.Product
-	err = json.NewDecoder(r.Body).Decode(&product)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	// stock dibaca terpisah supaya bisa dibedakan antara tidak dikirim dan dikirim 0
+	var product models.Product
+	var stockField struct {
+		Stock *float64 `json:"stock"`
+	}
+	if json.Unmarshal(body, &product) != nil || json.Unmarshal(body, &stockField) != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateProduct(&product); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	product.ID = id
-	err = h.service.Update(&product)
+	err = h.service.Update(&product, stockField.Stock)
+	if errors.Is(err, models.ErrProductNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrDuplicateSKU) || errors.Is(err, models.ErrDuplicateBarcode) || errors.Is(err, models.ErrDuplicatePLU) ||
+		errors.Is(err, models.ErrStockNotEditable) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, models.ErrInvalidQuantity) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -129,7 +426,184 @@ func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(product)
 }
 
-// Delete - DELETE /api/produk/{id}
+// Restore - POST /api/produk/{id}/restore
+func (h *ProductHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/produk/"), "/restore")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.service.Restore(id)
+	if errors.Is(err, models.ErrProductNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// AdjustStock - POST /api/produk/{id}/stock-adjustments
+func (h *ProductHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/produk/"), "/stock-adjustments")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.StockAdjustmentRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !models.IsManualStockMovementType(req.Type) {
+		http.Error(w, "type must be one of: "+strings.Join(models.ManualStockMovementTypes, ", "), http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+	req.Quantity = models.RoundQuantity(req.Quantity)
+	switch {
+	case req.Quantity == 0:
+		http.Error(w, "quantity must not be 0", http.StatusBadRequest)
+		return
+	case req.Type == models.StockMovementReceipt && req.Quantity < 0:
+		http.Error(w, "quantity must be positive for receipt", http.StatusBadRequest)
+		return
+	case req.Type == models.StockMovementDamage && req.Quantity > 0:
+		http.Error(w, "quantity must be negative for damage", http.StatusBadRequest)
+		return
+	}
+	req.BatchNumber = strings.TrimSpace(req.BatchNumber)
+	if req.ExpiryDate, err = models.ParseExpiryDate(req.ExpiryDate); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	movement, err := h.service.AdjustStock(id, req)
+	if errors.Is(err, models.ErrProductNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrOutletNotFound) || errors.Is(err, models.ErrOutletArchived) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, models.ErrProductArchived) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, models.ErrNegativeStock) || errors.Is(err, models.ErrInvalidQuantity) ||
+		errors.Is(err, models.ErrBatchRequired) || errors.Is(err, models.ErrBatchNotFound) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
+
+// GetBatches - GET /api/produk/{id}/batches?outlet_id=1&include_empty=true, urut FEFO
+func (h *ProductHandler) GetBatches(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/produk/"), "/batches")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	includeEmpty := false
+	if v := r.URL.Query().Get("include_empty"); v != "" {
+		includeEmpty, err = strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "include_empty must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	outletID, err := parseOutletID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	batches, err := h.service.GetBatches(id, outletID, includeEmpty)
+	if errors.Is(err, models.ErrProductNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
+
+// GetStockMovements - GET /api/produk/{id}/stock-movements?type=&outlet_id=&page=1&limit=20
+func (h *ProductHandler) GetStockMovements(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/produk/"), "/stock-movements")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := models.StockMovementFilter{
+		ProductID: id,
+		Type:      r.URL.Query().Get("type"),
+		Page:      page,
+		Limit:     limit,
+	}
+	if filter.Type != "" && !models.IsValidStockMovementType(filter.Type) {
+		http.Error(w, "type must be one of: "+strings.Join(models.StockMovementTypes, ", "), http.StatusBadRequest)
+		return
+	}
+	filter.OutletID, err = parseOutletID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.GetStockMovements(filter)
+	if errors.Is(err, models.ErrProductNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Delete - DELETE /api/produk/{id}, produk diarsipkan (soft delete)
 func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/produk/")
 	id, err := strconv.Atoi(idStr)
@@ -139,6 +613,10 @@ func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = h.service.Delete(id)
+	if errors.Is(err, models.ErrProductNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -146,6 +624,65 @@ func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Product deleted successfully",
+		"message": "Product archived successfully",
 	})
+}
+
+// validateProduct - cek harga, rapikan sku/plu, cek satuan dan pastikan setiap barcode punya check digit yang valid
+func validateProduct(product *models.Product) error {
+	if product.Price < 0 || product.CostPrice < 0 {
+		return errors.New("price and cost_price must not be negative")
+	}
+
+	product.SKU = strings.TrimSpace(product.SKU)
+	if len(product.SKU) > 64 {
+		return errors.New("sku must be at most 64 characters")
+	}
+
+	if product.Unit == "" {
+		product.Unit = models.UnitPcs
+	}
+	if !models.IsValidUnit(product.Unit) {
+		return errors.New("unit must be one of: " + strings.Join(models.Units, ", "))
+	}
+	if product.Stock < 0 || product.MinStock < 0 || product.ReorderQty < 0 {
+		return errors.New("stock, min_stock and reorder_qty must not be negative")
+	}
+	if product.Unit == models.UnitPcs {
+		for _, q := range []float64{product.Stock, product.MinStock, product.ReorderQty} {
+			if q != math.Trunc(q) {
+				return errors.New("stock, min_stock and reorder_qty must be whole numbers for products sold per pcs")
+			}
+		}
+	}
+	product.Stock = models.RoundQuantity(product.Stock)
+	product.MinStock = models.RoundQuantity(product.MinStock)
+	product.ReorderQty = models.RoundQuantity(product.ReorderQty)
+
+	// PLU disimpan tanpa nol di depan, sama seperti hasil decode label timbangan
+	product.PLU = strings.TrimLeft(strings.TrimSpace(product.PLU), "0")
+	for _, c := range product.PLU {
+		if c < '0' || c > '9' {
+			return errors.New("plu must contain digits only")
+		}
+	}
+
+	if product.Barcodes == nil {
+		product.Barcodes = make([]string, 0)
+	}
+
+	seen := make(map[string]bool)
+	for i, barcode := range product.Barcodes {
+		barcode = strings.TrimSpace(barcode)
+		if !models.IsValidBarcode(barcode) {
+			return fmt.Errorf("%w: %q", models.ErrInvalidBarcode, barcode)
+		}
+		if seen[barcode] {
+			return fmt.Errorf("barcode %s is listed more than once", barcode)
+		}
+		seen[barcode] = true
+		product.Barcodes[i] = barcode
+	}
+
+	return nil
 }
\ No newline at end of file
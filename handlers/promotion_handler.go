@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type PromotionHandler struct {
+	service *services.PromotionService
+}
+
+func NewPromotionHandler(service *services.PromotionService) *PromotionHandler {
+	return &PromotionHandler{service: service}
+}
+
+// HandlePromotions - GET/POST /api/promotions
+func (h *PromotionHandler) HandlePromotions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetAll - GET /api/promotions?include_archived=true
+func (h *PromotionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	includeArchived, err := parseIncludeArchived(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	promotions, err := h.service.GetAll(includeArchived)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotions)
+}
+
+func (h *PromotionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var promotion models.Promotion
+	err := json.NewDecoder(r.Body).Decode(&promotion)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validatePromotion(&promotion); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.Create(&promotion)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// HandlePromotionByID - GET/PUT/DELETE /api/promotions/{id}
+// POST /api/promotions/{id}/restore
+func (h *PromotionHandler) HandlePromotionByID(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/restore") {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.Restore(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.GetByID(w, r)
+	case http.MethodPut:
+		h.Update(w, r)
+	case http.MethodDelete:
+		h.Delete(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetByID - GET /api/promotions/{id}
+func (h *PromotionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/promotions/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := h.service.GetByID(id)
+	if errors.Is(err, models.ErrPromotionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// Update - PUT /api/promotions/{id}
+func (h *PromotionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/promotions/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	var promotion models.Promotion
+	err = json.NewDecoder(r.Body).Decode(&promotion)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validatePromotion(&promotion); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	promotion.ID = id
+	err = h.service.Update(&promotion)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// Restore - POST /api/promotions/{id}/restore
+func (h *PromotionHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/promotions/"), "/restore")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := h.service.Restore(id)
+	if errors.Is(err, models.ErrPromotionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// Delete - DELETE /api/promotions/{id}, promo diarsipkan (soft delete) dan berhenti berlaku
+func (h *PromotionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/promotions/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.Delete(id)
+	if errors.Is(err, models.ErrPromotionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Promotion archived successfully",
+	})
+}
+
+// validatePromotion - nama dan jenis wajib, field aksi dicek sesuai jenis (field jenis lain dikosongkan),
+// lalu syarat produk/kategori, quantity, tanggal, hari dan jam
+func validatePromotion(promotion *models.Promotion) error {
+	promotion.Name = strings.TrimSpace(promotion.Name)
+	if promotion.Name == "" {
+		return errors.New("name is required")
+	}
+	promotion.Description = strings.TrimSpace(promotion.Description)
+
+	value, buy, free, bundleQty, bundlePrice := promotion.Value, promotion.BuyQuantity, promotion.FreeQuantity, promotion.BundleQuantity, promotion.BundlePrice
+	promotion.Value, promotion.BuyQuantity, promotion.FreeQuantity, promotion.BundleQuantity, promotion.BundlePrice = 0, 0, 0, 0, 0
+	switch promotion.Type {
+	case models.PromotionTypePercent:
+		if value <= 0 || value > 100 {
+			return errors.New("value must be greater than 0 and at most 100 for percent promotions")
+		}
+		promotion.Value = value
+	case models.PromotionTypeFixed:
+		if value <= 0 || value != math.Trunc(value) {
+			return errors.New("value must be a whole rupiah amount greater than 0 for fixed promotions")
+		}
+		promotion.Value = value
+	case models.PromotionTypeBuyXGetY:
+		if buy < 1 || free < 1 {
+			return errors.New("buy_quantity and free_quantity must be at least 1")
+		}
+		promotion.BuyQuantity, promotion.FreeQuantity = buy, free
+	case models.PromotionTypeBundlePrice:
+		if bundleQty < 2 {
+			return errors.New("bundle_quantity must be at least 2")
+		}
+		if bundlePrice <= 0 {
+			return errors.New("bundle_price must be greater than 0")
+		}
+		promotion.BundleQuantity, promotion.BundlePrice = bundleQty, bundlePrice
+	default:
+		return errors.New("type must be one of: " + strings.Join(models.PromotionTypes, ", "))
+	}
+
+	var err error
+	if promotion.ProductIDs, err = uniquePositiveIDs(promotion.ProductIDs, "product_ids"); err != nil {
+		return err
+	}
+	if promotion.CategoryIDs, err = uniquePositiveIDs(promotion.CategoryIDs, "category_ids"); err != nil {
+		return err
+	}
+	promotion.MinQuantity = models.RoundQuantity(promotion.MinQuantity)
+	if promotion.MinQuantity < 0 {
+		return errors.New("min_quantity must not be negative")
+	}
+
+	if promotion.StartDate, err = parseOptionalTime(promotion.StartDate, "2006-01-02", "start_date must use YYYY-MM-DD format"); err != nil {
+		return err
+	}
+	if promotion.EndDate, err = parseOptionalTime(promotion.EndDate, "2006-01-02", "end_date must use YYYY-MM-DD format"); err != nil {
+		return err
+	}
+	if promotion.StartDate != nil && promotion.EndDate != nil && *promotion.EndDate < *promotion.StartDate {
+		return errors.New("end_date must not be before start_date")
+	}
+
+	seen := make(map[int]bool)
+	for _, d := range promotion.DaysOfWeek {
+		if d < 0 || d > 6 {
+			return errors.New("days_of_week must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		if seen[d] {
+			return errors.New("each day may only appear once in days_of_week")
+		}
+		seen[d] = true
+	}
+	if promotion.DaysOfWeek == nil {
+		promotion.DaysOfWeek = make([]int, 0)
+	}
+
+	if promotion.StartTime, err = parseOptionalTime(promotion.StartTime, "15:04", "start_time must use HH:MM format"); err != nil {
+		return err
+	}
+	if promotion.EndTime, err = parseOptionalTime(promotion.EndTime, "15:04", "end_time must use HH:MM format"); err != nil {
+		return err
+	}
+	if (promotion.StartTime == nil) != (promotion.EndTime == nil) {
+		return errors.New("start_time and end_time must be set together")
+	}
+	if promotion.StartTime != nil && *promotion.StartTime == *promotion.EndTime {
+		return errors.New("start_time and end_time must be different")
+	}
+	return nil
+}
+
+// parseOptionalTime - nil atau kosong berarti tidak diisi, selain itu harus sesuai layout (dinormalkan ke layout)
+func parseOptionalTime(value *string, layout, message string) (*string, error) {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(layout, strings.TrimSpace(*value))
+	if err != nil {
+		return nil, errors.New(message)
+	}
+	formatted := parsed.Format(layout)
+	return &formatted, nil
+}
+
+// uniquePositiveIDs - id harus positif dan tidak dobel, nil jadi slice kosong
+func uniquePositiveIDs(ids []int, field string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, id := range ids {
+		if id <= 0 {
+			return nil, fmt.Errorf("%s must contain positive ids", field)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("each id may only appear once in %s", field)
+		}
+		seen[id] = true
+	}
+	if ids == nil {
+		ids = make([]int, 0)
+	}
+	return ids, nil
+}
+
+func writePromotionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrPromotionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrProductNotFound), errors.Is(err, models.ErrCategoryNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
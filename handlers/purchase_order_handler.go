@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type PurchaseOrderHandler struct {
+	service *services.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(service *services.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{service: service}
+}
+
+// HandlePurchaseOrders - GET/POST /api/purchase-orders
+func (h *PurchaseOrderHandler) HandlePurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Create - POST /api/purchase-orders, PO dibuat sebagai draft
+func (h *PurchaseOrderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.PurchaseOrderRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validatePurchaseOrderRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.Create(req)
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// List - GET /api/purchase-orders?status=&supplier_id=&outlet_id=&page=1&limit=20
+func (h *PurchaseOrderHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := models.PurchaseOrderFilter{
+		Status: r.URL.Query().Get("status"),
+		Page:   page,
+		Limit:  limit,
+	}
+	if filter.Status != "" && !models.IsValidPurchaseOrderStatus(filter.Status) {
+		http.Error(w, "status must be one of: "+strings.Join(models.PurchaseOrderStatuses, ", "), http.StatusBadRequest)
+		return
+	}
+
+	supplierID, err := parseOptionalInt(r, "supplier_id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if supplierID != nil {
+		filter.SupplierID = *supplierID
+	}
+	if filter.OutletID, err = parseOutletID(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.List(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandlePurchaseOrderByID - GET/PUT /api/purchase-orders/{id}
+// POST /api/purchase-orders/{id}/order
+// POST /api/purchase-orders/{id}/cancel
+// POST /api/purchase-orders/{id}/receipts
+func (h *PurchaseOrderHandler) HandlePurchaseOrderByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/purchase-orders/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = strings.Join(parts[1:], "/")
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "" && r.Method == http.MethodPut:
+		h.Update(w, r, id)
+	case action == "order" && r.Method == http.MethodPost:
+		h.MarkOrdered(w, r, id)
+	case action == "cancel" && r.Method == http.MethodPost:
+		h.Cancel(w, r, id)
+	case action == "receipts" && r.Method == http.MethodPost:
+		h.Receive(w, r, id)
+	case action == "" || action == "order" || action == "cancel" || action == "receipts":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GetByID - GET /api/purchase-orders/{id}
+func (h *PurchaseOrderHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	order, err := h.service.GetByID(id)
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// Update - PUT /api/purchase-orders/{id}, hanya selama draft
+func (h *PurchaseOrderHandler) Update(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.PurchaseOrderRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validatePurchaseOrderRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.Update(id, req)
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// MarkOrdered - POST /api/purchase-orders/{id}/order
+func (h *PurchaseOrderHandler) MarkOrdered(w http.ResponseWriter, r *http.Request, id int) {
+	order, err := h.service.MarkOrdered(id)
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// Cancel - POST /api/purchase-orders/{id}/cancel
+func (h *PurchaseOrderHandler) Cancel(w http.ResponseWriter, r *http.Request, id int) {
+	order, err := h.service.Cancel(id)
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// Receive - POST /api/purchase-orders/{id}/receipts, body boleh kosong untuk menerima semua sisa item
+func (h *PurchaseOrderHandler) Receive(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.GoodsReceiptRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Notes = strings.TrimSpace(req.Notes)
+	seen := make(map[int]bool)
+	for i, item := range req.Items {
+		req.Items[i].Quantity = models.RoundQuantity(item.Quantity)
+		if req.Items[i].Quantity <= 0 {
+			http.Error(w, "quantity must be greater than 0", http.StatusBadRequest)
+			return
+		}
+		if item.UnitCost != nil && *item.UnitCost < 0 {
+			http.Error(w, "unit_cost must not be negative", http.StatusBadRequest)
+			return
+		}
+		req.Items[i].BatchNumber = strings.TrimSpace(item.BatchNumber)
+		if req.Items[i].ExpiryDate, err = models.ParseExpiryDate(item.ExpiryDate); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if seen[item.PurchaseOrderItemID] {
+			http.Error(w, "each purchase_order_item_id may only appear once", http.StatusBadRequest)
+			return
+		}
+		seen[item.PurchaseOrderItemID] = true
+	}
+
+	receipt, err := h.service.Receive(id, req)
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// validatePurchaseOrderRequest - supplier dan item wajib, quantity positif, unit_cost tidak negatif
+func validatePurchaseOrderRequest(req *models.PurchaseOrderRequest) error {
+	if req.SupplierID <= 0 {
+		return errors.New("supplier_id is required")
+	}
+	if req.OutletID < 0 {
+		return errors.New("outlet_id must be a positive integer")
+	}
+	if len(req.Items) == 0 {
+		return errors.New("items are required")
+	}
+	req.Notes = strings.TrimSpace(req.Notes)
+
+	seen := make(map[int]bool)
+	for i, item := range req.Items {
+		if item.ProductID <= 0 {
+			return errors.New("each item needs a product_id")
+		}
+		req.Items[i].Quantity = models.RoundQuantity(item.Quantity)
+		if req.Items[i].Quantity <= 0 {
+			return errors.New("quantity must be greater than 0")
+		}
+		if item.UnitCost < 0 {
+			return errors.New("unit_cost must not be negative")
+		}
+		if seen[item.ProductID] {
+			return errors.New("each product_id may only appear once")
+		}
+		seen[item.ProductID] = true
+	}
+	return nil
+}
+
+func writePurchaseOrderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrPurchaseOrderNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrSupplierNotFound), errors.Is(err, models.ErrSupplierArchived),
+		errors.Is(err, models.ErrOutletNotFound), errors.Is(err, models.ErrOutletArchived),
+		errors.Is(err, models.ErrProductNotFound), errors.Is(err, models.ErrProductArchived):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrInvalidPurchaseOrderStatus):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrOverReceipt), errors.Is(err, models.ErrInvalidQuantity), errors.Is(err, models.ErrBatchRequired):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
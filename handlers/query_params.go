@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parsePagination - baca query page & limit, default page 1 dan limit 20 (maksimal 100)
+func parsePagination(r *http.Request) (page, limit int, err error) {
+	page, limit = 1, defaultPageLimit
+
+	if v := r.URL.Query().Get("page"); v != "" {
+		page, err = strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("page must be a positive integer")
+		}
+	}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+	}
+
+	return page, limit, nil
+}
+
+// parseOptionalInt - baca query integer opsional, nil kalau tidak diisi
+func parseOptionalInt(r *http.Request, key string) (*int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer", key)
+	}
+	return &n, nil
+}
+
+// parseIncludeArchived - baca query include_archived, default false
+func parseIncludeArchived(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("include_archived")
+	if v == "" {
+		return false, nil
+	}
+
+	includeArchived, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("include_archived must be true or false")
+	}
+	return includeArchived, nil
+}
+
+// parseOutletID - baca query outlet_id, 0 kalau tidak diisi (semua outlet / outlet default)
+func parseOutletID(r *http.Request) (int, error) {
+	outletID, err := parseOptionalInt(r, "outlet_id")
+	if err != nil {
+		return 0, err
+	}
+	if outletID == nil {
+		return 0, nil
+	}
+	if *outletID <= 0 {
+		return 0, fmt.Errorf("outlet_id must be a positive integer")
+	}
+	return *outletID, nil
+}
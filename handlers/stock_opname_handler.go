@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type StockOpnameHandler struct {
+	service *services.StockOpnameService
+}
+
+func NewStockOpnameHandler(service *services.StockOpnameService) *StockOpnameHandler {
+	return &StockOpnameHandler{service: service}
+}
+
+// HandleStockOpnames - GET/POST /api/stock-opnames
+func (h *StockOpnameHandler) HandleStockOpnames(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Create - POST /api/stock-opnames, buka sesi dan snapshot stok sistem
+func (h *StockOpnameHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateStockOpnameRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Notes = strings.TrimSpace(req.Notes)
+	if req.OutletID < 0 {
+		http.Error(w, "outlet_id must be a positive integer", http.StatusBadRequest)
+		return
+	}
+
+	opname, err := h.service.Create(req)
+	if errors.Is(err, models.ErrCategoryNotFound) || errors.Is(err, models.ErrOutletNotFound) || errors.Is(err, models.ErrOutletArchived) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(opname)
+}
+
+// List - GET /api/stock-opnames?status=&outlet_id=&page=1&limit=20
+func (h *StockOpnameHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := models.StockOpnameFilter{
+		Status: r.URL.Query().Get("status"),
+		Page:   page,
+		Limit:  limit,
+	}
+	if filter.Status != "" && !models.IsValidStockOpnameStatus(filter.Status) {
+		http.Error(w, "status must be one of: "+strings.Join(models.StockOpnameStatuses, ", "), http.StatusBadRequest)
+		return
+	}
+	if filter.OutletID, err = parseOutletID(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.List(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleStockOpnameByID - GET /api/stock-opnames/{id}
+// POST /api/stock-opnames/{id}/counts
+// POST /api/stock-opnames/{id}/post
+// POST /api/stock-opnames/{id}/cancel
+func (h *StockOpnameHandler) HandleStockOpnameByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/stock-opnames/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid stock opname ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = strings.Join(parts[1:], "/")
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "counts" && r.Method == http.MethodPost:
+		h.AddCounts(w, r, id)
+	case action == "post" && r.Method == http.MethodPost:
+		h.Post(w, r, id)
+	case action == "cancel" && r.Method == http.MethodPost:
+		h.Cancel(w, r, id)
+	case action == "" || action == "counts" || action == "post" || action == "cancel":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GetByID - GET /api/stock-opnames/{id}?variance_only=true
+// variance_only hanya menampilkan produk yang sudah dihitung dan ada selisihnya, summary tetap untuk seluruh sesi.
+func (h *StockOpnameHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	varianceOnly := false
+	if v := r.URL.Query().Get("variance_only"); v != "" {
+		var err error
+		varianceOnly, err = strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "variance_only must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	opname, err := h.service.GetByID(id)
+	if errors.Is(err, models.ErrStockOpnameNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if varianceOnly {
+		items := make([]models.StockOpnameItem, 0)
+		for _, item := range opname.Items {
+			if item.Variance != nil && *item.Variance != 0 {
+				items = append(items, item)
+			}
+		}
+		opname.Items = items
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(opname)
+}
+
+// AddCounts - POST /api/stock-opnames/{id}/counts
+func (h *StockOpnameHandler) AddCounts(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.StockOpnameCountRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Items) == 0 {
+		http.Error(w, "items are required", http.StatusBadRequest)
+		return
+	}
+	req.CountedBy = strings.TrimSpace(req.CountedBy)
+	for i, item := range req.Items {
+		if item.ProductID <= 0 {
+			http.Error(w, "each item needs a product_id", http.StatusBadRequest)
+			return
+		}
+		req.Items[i].Quantity = models.RoundQuantity(item.Quantity)
+	}
+
+	opname, err := h.service.AddCounts(id, req)
+	if err != nil {
+		writeStockOpnameError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(opname)
+}
+
+// Post - POST /api/stock-opnames/{id}/post, sesuaikan stok dan kembalikan laporan selisih
+func (h *StockOpnameHandler) Post(w http.ResponseWriter, r *http.Request, id int) {
+	opname, err := h.service.Post(id)
+	if err != nil {
+		writeStockOpnameError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(opname)
+}
+
+// Cancel - POST /api/stock-opnames/{id}/cancel
+func (h *StockOpnameHandler) Cancel(w http.ResponseWriter, r *http.Request, id int) {
+	opname, err := h.service.Cancel(id)
+	if err != nil {
+		writeStockOpnameError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(opname)
+}
+
+func writeStockOpnameError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrStockOpnameNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrStockOpnameNotOpen):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrProductNotInOpname), errors.Is(err, models.ErrInvalidQuantity), errors.Is(err, models.ErrNegativeStock):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type StockTransferHandler struct {
+	service *services.StockTransferService
+}
+
+func NewStockTransferHandler(service *services.StockTransferService) *StockTransferHandler {
+	return &StockTransferHandler{service: service}
+}
+
+// HandleStockTransfers - GET/POST /api/stock-transfers
+func (h *StockTransferHandler) HandleStockTransfers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Create - POST /api/stock-transfers, transfer dibuat sebagai draft
+func (h *StockTransferHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.StockTransferRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateStockTransferRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.service.Create(req)
+	if err != nil {
+		writeStockTransferError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// List - GET /api/stock-transfers?status=&outlet_id=&page=1&limit=20
+func (h *StockTransferHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := models.StockTransferFilter{
+		Status: r.URL.Query().Get("status"),
+		Page:   page,
+		Limit:  limit,
+	}
+	if filter.Status != "" && !models.IsValidStockTransferStatus(filter.Status) {
+		http.Error(w, "status must be one of: "+strings.Join(models.StockTransferStatuses, ", "), http.StatusBadRequest)
+		return
+	}
+	if filter.OutletID, err = parseOutletID(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.List(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleStockTransferByID - GET /api/stock-transfers/{id}
+// POST /api/stock-transfers/{id}/dispatch
+// POST /api/stock-transfers/{id}/receive
+// POST /api/stock-transfers/{id}/cancel
+func (h *StockTransferHandler) HandleStockTransferByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/stock-transfers/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid stock transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = strings.Join(parts[1:], "/")
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "dispatch" && r.Method == http.MethodPost:
+		h.Dispatch(w, r, id)
+	case action == "receive" && r.Method == http.MethodPost:
+		h.Receive(w, r, id)
+	case action == "cancel" && r.Method == http.MethodPost:
+		h.Cancel(w, r, id)
+	case action == "" || action == "dispatch" || action == "receive" || action == "cancel":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GetByID - GET /api/stock-transfers/{id}
+func (h *StockTransferHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	transfer, err := h.service.GetByID(id)
+	if err != nil {
+		writeStockTransferError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// Dispatch - POST /api/stock-transfers/{id}/dispatch, stok keluar dari outlet asal
+func (h *StockTransferHandler) Dispatch(w http.ResponseWriter, r *http.Request, id int) {
+	transfer, err := h.service.Dispatch(id)
+	if err != nil {
+		writeStockTransferError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// Receive - POST /api/stock-transfers/{id}/receive, body boleh kosong kalau semua item diterima lengkap
+func (h *StockTransferHandler) Receive(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.StockTransferReceiveRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Notes = strings.TrimSpace(req.Notes)
+	seen := make(map[int]bool)
+	for i, item := range req.Items {
+		req.Items[i].ReceivedQuantity = models.RoundQuantity(item.ReceivedQuantity)
+		if req.Items[i].ReceivedQuantity < 0 {
+			http.Error(w, "received_quantity must not be negative", http.StatusBadRequest)
+			return
+		}
+		req.Items[i].Note = strings.TrimSpace(item.Note)
+		if seen[item.StockTransferItemID] {
+			http.Error(w, "each stock_transfer_item_id may only appear once", http.StatusBadRequest)
+			return
+		}
+		seen[item.StockTransferItemID] = true
+	}
+
+	transfer, err := h.service.Receive(id, req)
+	if err != nil {
+		writeStockTransferError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// Cancel - POST /api/stock-transfers/{id}/cancel, hanya selama draft
+func (h *StockTransferHandler) Cancel(w http.ResponseWriter, r *http.Request, id int) {
+	transfer, err := h.service.Cancel(id)
+	if err != nil {
+		writeStockTransferError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// validateStockTransferRequest - outlet asal/tujuan dan item wajib, quantity positif, produk tidak dobel
+func validateStockTransferRequest(req *models.StockTransferRequest) error {
+	if req.SourceOutletID <= 0 {
+		return errors.New("source_outlet_id is required")
+	}
+	if req.DestinationOutletID <= 0 {
+		return errors.New("destination_outlet_id is required")
+	}
+	if len(req.Items) == 0 {
+		return errors.New("items are required")
+	}
+	req.Notes = strings.TrimSpace(req.Notes)
+
+	seen := make(map[int]bool)
+	for i, item := range req.Items {
+		if item.ProductID <= 0 {
+			return errors.New("each item needs a product_id")
+		}
+		req.Items[i].Quantity = models.RoundQuantity(item.Quantity)
+		if req.Items[i].Quantity <= 0 {
+			return errors.New("quantity must be greater than 0")
+		}
+		if seen[item.ProductID] {
+			return errors.New("each product_id may only appear once")
+		}
+		seen[item.ProductID] = true
+	}
+	return nil
+}
+
+func writeStockTransferError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrStockTransferNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrSameOutletTransfer),
+		errors.Is(err, models.ErrOutletNotFound), errors.Is(err, models.ErrOutletArchived),
+		errors.Is(err, models.ErrProductNotFound), errors.Is(err, models.ErrProductArchived):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrInvalidStockTransferStatus):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrNegativeStock), errors.Is(err, models.ErrInvalidQuantity):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -2,18 +2,33 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"kasir-api/models"
 	"kasir-api/services"
 )
 
+// TransactionHandler - roleHeader adalah header berisi role kasir yang di-set gateway/proxy setelah autentikasi.
+// Kosong berarti semua checkout memakai DefaultDiscountRole.
 type TransactionHandler struct {
-	service *services.TransactionService
+	service    *services.TransactionService
+	roleHeader string
 }
 
-func NewTransactionHandler(service *services.TransactionService) *TransactionHandler {
-	return &TransactionHandler{service: service}
+func NewTransactionHandler(service *services.TransactionService, roleHeader string) *TransactionHandler {
+	return &TransactionHandler{service: service, roleHeader: roleHeader}
+}
+
+// role - role untuk batas diskon, hanya dari header yang dikontrol server, tidak pernah dari body request
+func (h *TransactionHandler) role(r *http.Request) string {
+	if h.roleHeader == "" {
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get(h.roleHeader))
 }
 
 // multiple item apa aja, quantity nya
@@ -34,7 +49,244 @@ func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	transaction, err := h.service.Checkout(req.Items)
+	if err := validateCart(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Role = h.role(r)
+	if len(req.Payments) == 0 {
+		http.Error(w, "payments are required", http.StatusBadRequest)
+		return
+	}
+	for _, payment := range req.Payments {
+		if !models.IsValidPaymentMethod(payment.Method) {
+			http.Error(w, "payment method must be one of: "+strings.Join(models.PaymentMethods, ", "), http.StatusBadRequest)
+			return
+		}
+		if payment.Amount <= 0 {
+			http.Error(w, "payment amount must be greater than 0", http.StatusBadRequest)
+			return
+		}
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if len(idempotencyKey) > 255 {
+		http.Error(w, "Idempotency-Key must be at most 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	transaction, replayed, err := h.service.Checkout(req, idempotencyKey)
+	if err != nil {
+		writeCheckoutError(w, err)
+		return
+	}
+
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// HandlePriceCart - POST /api/checkout/price
+func (h *TransactionHandler) HandlePriceCart(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.PriceCart(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PriceCart - hitung harga cart (promo dan diskon) tanpa checkout, payments tidak diperlukan
+func (h *TransactionHandler) PriceCart(w http.ResponseWriter, r *http.Request) {
+	var req models.CheckoutRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateCart(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Role = h.role(r)
+
+	price, err := h.service.PriceCart(req)
+	if err != nil {
+		writeCheckoutError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(price)
+}
+
+// validateCart - item, outlet dan diskon cart, dipakai checkout dan hitung harga cart
+func validateCart(req *models.CheckoutRequest) error {
+	if len(req.Items) == 0 {
+		return errors.New("items are required")
+	}
+	if req.OutletID < 0 {
+		return errors.New("outlet_id must be a positive integer")
+	}
+	for _, item := range req.Items {
+		if item.ProductID == 0 && item.Barcode == "" {
+			return errors.New("each item needs a product_id or barcode")
+		}
+		if item.Quantity <= 0 {
+			return errors.New("quantity must be greater than 0")
+		}
+		if item.Discount != nil {
+			if err := item.Discount.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	if req.Discount != nil {
+		if err := req.Discount.Validate(); err != nil {
+			return err
+		}
+	}
+	req.CustomerRef = strings.TrimSpace(req.CustomerRef)
+	req.VoucherCode = models.NormalizeVoucherCode(req.VoucherCode)
+	return nil
+}
+
+func writeCheckoutError(w http.ResponseWriter, err error) {
+	var stockErr *models.InsufficientStockError
+	switch {
+	case errors.Is(err, models.ErrIdempotencyKeyReused), errors.Is(err, models.ErrInvalidPayment), errors.Is(err, models.ErrInvalidQuantity),
+		errors.Is(err, models.ErrInvalidDiscount), errors.Is(err, models.ErrVoucherNotApplicable), errors.Is(err, models.ErrVoucherArchived),
+		errors.Is(err, models.ErrUnknownTaxCategory), errors.Is(err, models.ErrInvalidScaleLabel):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.As(err, &stockErr):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "insufficient stock",
+			"items": stockErr.Items,
+		})
+	case errors.Is(err, models.ErrProductNotFound), errors.Is(err, models.ErrVoucherNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrProductArchived), errors.Is(err, models.ErrVoucherLimitReached):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrOutletNotFound), errors.Is(err, models.ErrOutletArchived), errors.Is(err, models.ErrUnknownDiscountRole),
+		errors.Is(err, models.ErrCustomerRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrDiscountLimitExceeded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// HandleTransactions - GET /api/transactions
+func (h *TransactionHandler) HandleTransactions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// List - GET /api/transactions?page=1&limit=20&start_date=&end_date=&min_total=&max_total=&product_id=&outlet_id=
+func (h *TransactionHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := models.TransactionFilter{
+		Page:      page,
+		Limit:     limit,
+		StartDate: r.URL.Query().Get("start_date"),
+		EndDate:   r.URL.Query().Get("end_date"),
+	}
+
+	for _, date := range []string{filter.StartDate, filter.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			http.Error(w, "start_date and end_date must use YYYY-MM-DD format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if filter.MinTotal, err = parseOptionalInt(r, "min_total"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.MaxTotal, err = parseOptionalInt(r, "max_total"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	productID, err := parseOptionalInt(r, "product_id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if productID != nil {
+		filter.ProductID = *productID
+	}
+
+	if filter.OutletID, err = parseOutletID(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.List(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleTransactionByID - GET /api/transactions/{id}
+// POST /api/transactions/{id}/void
+// POST /api/transactions/{id}/refunds
+func (h *TransactionHandler) HandleTransactionByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/transactions/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = strings.Join(parts[1:], "/")
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "void" && r.Method == http.MethodPost:
+		h.Void(w, r, id)
+	case action == "refunds" && r.Method == http.MethodPost:
+		h.Refund(w, r, id)
+	case action == "" || action == "void" || action == "refunds":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GetByID - GET /api/transactions/{id}
+func (h *TransactionHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	transaction, err := h.service.GetByID(id)
+	if errors.Is(err, models.ErrTransactionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -44,7 +296,84 @@ func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(transaction)
 }
 
-// HandleSalesReport - GET /api/report/hari-ini
+// Void - POST /api/transactions/{id}/void
+func (h *TransactionHandler) Void(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.VoidRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(req.Reason) == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.service.Void(id, req.Reason)
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
+// Refund - POST /api/transactions/{id}/refunds
+func (h *TransactionHandler) Refund(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.RefundRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(req.Reason) == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Items) == 0 {
+		http.Error(w, "items are required", http.StatusBadRequest)
+		return
+	}
+	seen := make(map[int]bool)
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			http.Error(w, "quantity must be greater than 0", http.StatusBadRequest)
+			return
+		}
+		if seen[item.TransactionDetailID] {
+			http.Error(w, "each transaction_detail_id may only appear once", http.StatusBadRequest)
+			return
+		}
+		seen[item.TransactionDetailID] = true
+	}
+
+	refund, err := h.service.Refund(id, req)
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
+func writeRefundError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrTransactionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrRefundNotAllowed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// HandleSalesReport - GET /api/report/hari-ini?outlet_id=1
 func (h *TransactionHandler) HandleSalesReport(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -55,7 +384,13 @@ func (h *TransactionHandler) HandleSalesReport(w http.ResponseWriter, r *http.Re
 }
 
 func (h *TransactionHandler) GetSalesSummaryToday(w http.ResponseWriter, r *http.Request) {
-	summary, err := h.service.GetSalesSummaryToday()
+	outletID, err := parseOutletID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.service.GetSalesSummaryToday(outletID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -65,7 +400,7 @@ func (h *TransactionHandler) GetSalesSummaryToday(w http.ResponseWriter, r *http
 	json.NewEncoder(w).Encode(summary)
 }
 
-// HandleReportByDateRange - GET /api/report?start_date=2026-01-01&end_date=2026-02-01
+// HandleReportByDateRange - GET /api/report?start_date=2026-01-01&end_date=2026-02-01&outlet_id=1
 func (h *TransactionHandler) HandleReportByDateRange(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -80,7 +415,13 @@ func (h *TransactionHandler) HandleReportByDateRange(w http.ResponseWriter, r *h
 		return
 	}
 
-	summary, err := h.service.GetSalesSummaryByDateRange(startDate, endDate)
+	outletID, err := parseOutletID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.service.GetSalesSummaryByDateRange(startDate, endDate, outletID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
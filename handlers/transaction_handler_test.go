@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kasir-api/models"
+)
+
+func TestWriteCheckoutError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			"insufficient stock",
+			&models.InsufficientStockError{Items: []models.StockShortage{{ProductID: 7, Requested: 3, Available: 2}}},
+			http.StatusConflict,
+		},
+		{"idempotency key reused", models.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity},
+		{"wrapped product not found", fmt.Errorf("%w: product id 7", models.ErrProductNotFound), http.StatusNotFound},
+		{"voucher limit reached", models.ErrVoucherLimitReached, http.StatusConflict},
+		{"unknown role", fmt.Errorf("%w: intern", models.ErrUnknownDiscountRole), http.StatusBadRequest},
+		{"discount above role limit", models.ErrDiscountLimitExceeded, http.StatusForbidden},
+		{"unexpected error", errors.New("connection reset"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeCheckoutError(w, tt.err)
+			if w.Code != tt.want {
+				t.Fatalf("writeCheckoutError(%v) status = %d, want %d", tt.err, w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCheckoutErrorInsufficientStockBody(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeCheckoutError(w, &models.InsufficientStockError{Items: []models.StockShortage{
+		{ProductID: 7, Requested: 3, Available: 2},
+		{ProductID: 9, Requested: 1.5, Available: 0},
+	}})
+
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", got)
+	}
+	var body struct {
+		Error string                 `json:"error"`
+		Items []models.StockShortage `json:"items"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Error != "insufficient stock" || len(body.Items) != 2 || body.Items[1].ProductID != 9 || body.Items[1].Requested != 1.5 {
+		t.Fatalf("body = %+v, want both shortages", body)
+	}
+}
+
+func TestWriteRefundError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"above remaining quantity", fmt.Errorf("%w: detail id 87 only has 1 left to refund", models.ErrRefundNotAllowed), http.StatusConflict},
+		{"already voided", fmt.Errorf("%w: transaction is already voided", models.ErrRefundNotAllowed), http.StatusConflict},
+		{"transaction not found", models.ErrTransactionNotFound, http.StatusNotFound},
+		{"unexpected error", errors.New("connection reset"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeRefundError(w, tt.err)
+			if w.Code != tt.want {
+				t.Fatalf("writeRefundError(%v) status = %d, want %d", tt.err, w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// Body refund yang tidak valid ditolak sebelum sampai ke service
+func TestRefundRejectsInvalidItems(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing reason", `{"items": [{"transaction_detail_id": 1, "quantity": 1}]}`},
+		{"no items", `{"reason": "Barang rusak", "items": []}`},
+		{"zero quantity", `{"reason": "Barang rusak", "items": [{"transaction_detail_id": 1, "quantity": 0}]}`},
+		{"negative quantity", `{"reason": "Barang rusak", "items": [{"transaction_detail_id": 1, "quantity": -1}]}`},
+		{"duplicate line", `{"reason": "Barang rusak", "items": [{"transaction_detail_id": 1, "quantity": 1}, {"transaction_detail_id": 1, "quantity": 1}]}`},
+	}
+
+	h := &TransactionHandler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/transactions/42/refunds", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.Refund(w, r, 42)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Refund(%s) status = %d, want %d", tt.body, w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type VoucherHandler struct {
+	service *services.VoucherService
+}
+
+func NewVoucherHandler(service *services.VoucherService) *VoucherHandler {
+	return &VoucherHandler{service: service}
+}
+
+// HandleVouchers - GET/POST /api/vouchers
+func (h *VoucherHandler) HandleVouchers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetAll - GET /api/vouchers?include_archived=true
+func (h *VoucherHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	includeArchived, err := parseIncludeArchived(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vouchers, err := h.service.GetAll(includeArchived)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vouchers)
+}
+
+func (h *VoucherHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var voucher models.Voucher
+	err := json.NewDecoder(r.Body).Decode(&voucher)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateVoucher(&voucher); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.Create(&voucher)
+	if err != nil {
+		writeVoucherError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(voucher)
+}
+
+// HandleVoucherByID - GET/PUT/DELETE /api/vouchers/{id}
+// POST /api/vouchers/{id}/restore
+func (h *VoucherHandler) HandleVoucherByID(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/restore") {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.Restore(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.GetByID(w, r)
+	case http.MethodPut:
+		h.Update(w, r)
+	case http.MethodDelete:
+		h.Delete(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetByID - GET /api/vouchers/{id}
+func (h *VoucherHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/vouchers/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid voucher ID", http.StatusBadRequest)
+		return
+	}
+
+	voucher, err := h.service.GetByID(id)
+	if errors.Is(err, models.ErrVoucherNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(voucher)
+}
+
+// Update - PUT /api/vouchers/{id}
+func (h *VoucherHandler) Update(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/vouchers/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid voucher ID", http.StatusBadRequest)
+		return
+	}
+
+	var voucher models.Voucher
+	err = json.NewDecoder(r.Body).Decode(&voucher)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateVoucher(&voucher); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	voucher.ID = id
+	err = h.service.Update(&voucher)
+	if err != nil {
+		writeVoucherError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(voucher)
+}
+
+// Restore - POST /api/vouchers/{id}/restore
+func (h *VoucherHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/vouchers/"), "/restore")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid voucher ID", http.StatusBadRequest)
+		return
+	}
+
+	voucher, err := h.service.Restore(id)
+	if errors.Is(err, models.ErrVoucherNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(voucher)
+}
+
+// Delete - DELETE /api/vouchers/{id}, voucher diarsipkan (soft delete) dan tidak bisa ditukarkan lagi
+func (h *VoucherHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/vouchers/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid voucher ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.Delete(id)
+	if errors.Is(err, models.ErrVoucherNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Voucher archived successfully",
+	})
+}
+
+// validateVoucher - kode wajib dan disimpan huruf besar, nilai dicek sesuai jenis,
+// batas dan minimum belanja tidak boleh negatif
+func validateVoucher(voucher *models.Voucher) error {
+	voucher.Code = models.NormalizeVoucherCode(voucher.Code)
+	if voucher.Code == "" {
+		return errors.New("code is required")
+	}
+	if strings.ContainsAny(voucher.Code, " \t\n") {
+		return errors.New("code must not contain spaces")
+	}
+	voucher.Description = strings.TrimSpace(voucher.Description)
+
+	switch voucher.Type {
+	case models.VoucherTypePercent:
+		if voucher.Value <= 0 || voucher.Value > 100 {
+			return errors.New("value must be greater than 0 and at most 100 for percent vouchers")
+		}
+	case models.VoucherTypeFixed:
+		if voucher.Value <= 0 || voucher.Value != math.Trunc(voucher.Value) {
+			return errors.New("value must be a whole rupiah amount greater than 0 for fixed vouchers")
+		}
+		// Batas rupiah hanya berarti untuk voucher persen
+		voucher.MaxDiscount = 0
+	default:
+		return errors.New("type must be " + models.VoucherTypePercent + " or " + models.VoucherTypeFixed)
+	}
+
+	if voucher.MaxDiscount < 0 || voucher.MinSpend < 0 {
+		return errors.New("max_discount and min_spend must not be negative")
+	}
+	if voucher.UsageLimit < 0 || voucher.PerCustomerLimit < 0 {
+		return errors.New("usage_limit and per_customer_limit must not be negative")
+	}
+
+	var err error
+	if voucher.StartDate, err = parseOptionalTime(voucher.StartDate, "2006-01-02", "start_date must use YYYY-MM-DD format"); err != nil {
+		return err
+	}
+	if voucher.EndDate, err = parseOptionalTime(voucher.EndDate, "2006-01-02", "end_date must use YYYY-MM-DD format"); err != nil {
+		return err
+	}
+	if voucher.StartDate != nil && voucher.EndDate != nil && *voucher.EndDate < *voucher.StartDate {
+		return errors.New("end_date must not be before start_date")
+	}
+	return nil
+}
+
+func writeVoucherError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrVoucherNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrDuplicateVoucher):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// HandleUsageReport - GET /api/report/vouchers?start_date=2026-01-01&end_date=2026-02-01&outlet_id=1
+func (h *VoucherHandler) HandleUsageReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	if startDate == "" || endDate == "" {
+		http.Error(w, "start_date and end_date are required", http.StatusBadRequest)
+		return
+	}
+
+	outletID, err := parseOutletID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetUsageReport(startDate, endDate, outletID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
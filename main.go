@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"kasir-api/database"
 	"kasir-api/handlers"
+	"kasir-api/models"
 	"kasir-api/repositories"
 	"kasir-api/services"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -18,6 +20,16 @@ import (
 type Config struct {
 	Port string `mapstructure:"PORT"`
 	DBConn string `mapstructure:"DB_CONN"`
+	IdempotencyKeyTTL time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL"`
+	ScaleBarcodeFormats string `mapstructure:"SCALE_BARCODE_FORMATS"`
+	DiscountLimits string `mapstructure:"DISCOUNT_LIMITS"`
+	TaxRates string `mapstructure:"TAX_RATES"`
+	PricesIncludeTax bool `mapstructure:"PRICES_INCLUDE_TAX"`
+	ServiceChargePercent float64 `mapstructure:"SERVICE_CHARGE_PERCENT"`
+	TaxRounding string `mapstructure:"TAX_ROUNDING"`
+	CashRoundingMode string `mapstructure:"CASH_ROUNDING_MODE"`
+	CashRoundingUnit int `mapstructure:"CASH_ROUNDING_UNIT"`
+	RoleHeader string `mapstructure:"ROLE_HEADER"`
 }
 
 func main(){
@@ -32,8 +44,83 @@ func main(){
 	config := Config{
  		Port: viper.GetString("PORT"),
 		DBConn: viper.GetString("DB_CONN"),
+		IdempotencyKeyTTL: viper.GetDuration("IDEMPOTENCY_KEY_TTL"),
+		ScaleBarcodeFormats: viper.GetString("SCALE_BARCODE_FORMATS"),
+		DiscountLimits: viper.GetString("DISCOUNT_LIMITS"),
+		TaxRates: viper.GetString("TAX_RATES"),
+		PricesIncludeTax: viper.GetBool("PRICES_INCLUDE_TAX"),
+		ServiceChargePercent: viper.GetFloat64("SERVICE_CHARGE_PERCENT"),
+		TaxRounding: viper.GetString("TAX_ROUNDING"),
+		CashRoundingMode: viper.GetString("CASH_ROUNDING_MODE"),
+		CashRoundingUnit: viper.GetInt("CASH_ROUNDING_UNIT"),
+		RoleHeader: viper.GetString("ROLE_HEADER"),
 	}
 
+	if config.IdempotencyKeyTTL <= 0 {
+		config.IdempotencyKeyTTL = 24 * time.Hour
+	}
+
+	// Format label timbangan: prefix:plu_length:weight|price:decimals
+	if !viper.IsSet("SCALE_BARCODE_FORMATS") {
+		config.ScaleBarcodeFormats = "20:5:weight:3,21:5:price:0"
+	}
+	scaleFormats, err := models.ParseScaleBarcodeFormats(config.ScaleBarcodeFormats)
+	if err != nil {
+		log.Fatal("Invalid SCALE_BARCODE_FORMATS:", err)
+	}
+
+	// Batas diskon per role: role:max_percent
+	if !viper.IsSet("DISCOUNT_LIMITS") {
+		config.DiscountLimits = "cashier:10,supervisor:25,manager:100"
+	}
+	discountLimits, err := models.ParseDiscountLimits(config.DiscountLimits)
+	if err != nil {
+		log.Fatal("Invalid DISCOUNT_LIMITS:", err)
+	}
+
+	// Tarif pajak per kategori pajak produk: category:rate_percent (PPN 11%)
+	if !viper.IsSet("TAX_RATES") {
+		config.TaxRates = "standard:11,exempt:0"
+	}
+	taxRates, err := models.ParseTaxRates(config.TaxRates)
+	if err != nil {
+		log.Fatal("Invalid TAX_RATES:", err)
+	}
+	if _, ok := taxRates[models.DefaultTaxCategory]; !ok {
+		log.Fatal("Invalid TAX_RATES: missing the default category ", models.DefaultTaxCategory)
+	}
+	// Harga jual umumnya sudah termasuk PPN
+	if !viper.IsSet("PRICES_INCLUDE_TAX") {
+		config.PricesIncludeTax = true
+	}
+	if config.ServiceChargePercent < 0 || config.ServiceChargePercent > 100 {
+		log.Fatal("Invalid SERVICE_CHARGE_PERCENT: must be between 0 and 100")
+	}
+	if config.TaxRounding == "" {
+		config.TaxRounding = models.RoundingNearest
+	}
+	if !models.IsValidRoundingMode(config.TaxRounding) {
+		log.Fatal("Invalid TAX_ROUNDING: must be one of ", strings.Join(models.RoundingModes, ", "))
+	}
+	taxConfig := models.TaxConfig{
+		Rates:                taxRates,
+		Inclusive:            config.PricesIncludeTax,
+		ServiceChargePercent: config.ServiceChargePercent,
+		Rounding:             config.TaxRounding,
+	}
+
+	// Pembulatan pembayaran tunai ke kelipatan unit rupiah, 0 berarti tanpa pembulatan
+	if config.CashRoundingMode == "" {
+		config.CashRoundingMode = models.RoundingNearest
+	}
+	if !models.IsValidRoundingMode(config.CashRoundingMode) {
+		log.Fatal("Invalid CASH_ROUNDING_MODE: must be one of ", strings.Join(models.RoundingModes, ", "))
+	}
+	if config.CashRoundingUnit < 0 {
+		log.Fatal("Invalid CASH_ROUNDING_UNIT: must not be negative")
+	}
+	cashRounding := models.CashRounding{Mode: config.CashRoundingMode, Unit: config.CashRoundingUnit}
+
 	// Setup database
 	db, err := database.InitDB(config.DBConn)
 	if err != nil {
@@ -55,26 +142,99 @@ func main(){
 			"endpoints": map[string]interface{}{
 				"health": "/health",
 				"products": map[string]string{
-					"list":   "GET /api/produk",
+					"list":   "GET /api/produk?page={page}&limit={limit}&sort={name|price|stock|-price}&min_price={amount}&max_price={amount}&in_stock=true&outlet_id={id}&include_archived=true",
 					"search": "GET /api/produk?name={keyword}",
+					"filter_by_category": "GET /api/produk?category_id={id}",
 					"create": "POST /api/produk",
 					"detail": "GET /api/produk/{id}",
 					"update": "PUT /api/produk/{id}",
 					"delete": "DELETE /api/produk/{id}",
+					"restore": "POST /api/produk/{id}/restore",
+					"low_stock": "GET /api/produk/low-stock?category_id={id}&outlet_id={id}&page={page}&limit={limit}",
+					"stock_adjustment": "POST /api/produk/{id}/stock-adjustments",
+					"stock_movements": "GET /api/produk/{id}/stock-movements?type={type}&outlet_id={id}&page={page}&limit={limit}",
+					"batches": "GET /api/produk/{id}/batches?outlet_id={id}&include_empty=true",
 				},
 				"categories": map[string]string{
-					"list":   "GET /api/categories",
+					"list":   "GET /api/categories?include_archived=true",
 					"create": "POST /api/categories",
 					"detail": "GET /api/categories/{id}",
 					"update": "PUT /api/categories/{id}",
-					"delete": "DELETE /api/categories/{id}",
+					"delete": "DELETE /api/categories/{id}?reassign_to={target_id}",
+					"products": "GET /api/categories/{id}/products",
+					"restore": "POST /api/categories/{id}/restore",
+				},
+				"outlets": map[string]string{
+					"list":    "GET /api/outlets?include_archived=true",
+					"create":  "POST /api/outlets",
+					"detail":  "GET /api/outlets/{id}",
+					"update":  "PUT /api/outlets/{id}",
+					"delete":  "DELETE /api/outlets/{id}",
+					"restore": "POST /api/outlets/{id}/restore",
+				},
+				"suppliers": map[string]string{
+					"list":    "GET /api/suppliers?include_archived=true",
+					"create":  "POST /api/suppliers",
+					"detail":  "GET /api/suppliers/{id}",
+					"update":  "PUT /api/suppliers/{id}",
+					"delete":  "DELETE /api/suppliers/{id}",
+					"restore": "POST /api/suppliers/{id}/restore",
+				},
+				"purchase_orders": map[string]string{
+					"list":    "GET /api/purchase-orders?status={status}&supplier_id={id}&outlet_id={id}&page={page}&limit={limit}",
+					"create":  "POST /api/purchase-orders",
+					"detail":  "GET /api/purchase-orders/{id}",
+					"update":  "PUT /api/purchase-orders/{id}",
+					"order":   "POST /api/purchase-orders/{id}/order",
+					"cancel":  "POST /api/purchase-orders/{id}/cancel",
+					"receive": "POST /api/purchase-orders/{id}/receipts",
+				},
+				"stock_opnames": map[string]string{
+					"list":   "GET /api/stock-opnames?status={open|posted|cancelled}&outlet_id={id}&page={page}&limit={limit}",
+					"create": "POST /api/stock-opnames",
+					"detail": "GET /api/stock-opnames/{id}?variance_only=true",
+					"counts": "POST /api/stock-opnames/{id}/counts",
+					"post":   "POST /api/stock-opnames/{id}/post",
+					"cancel": "POST /api/stock-opnames/{id}/cancel",
+				},
+				"stock_transfers": map[string]string{
+					"list":     "GET /api/stock-transfers?status={draft|in_transit|received|cancelled}&outlet_id={id}&page={page}&limit={limit}",
+					"create":   "POST /api/stock-transfers",
+					"detail":   "GET /api/stock-transfers/{id}",
+					"dispatch": "POST /api/stock-transfers/{id}/dispatch",
+					"receive":  "POST /api/stock-transfers/{id}/receive",
+					"cancel":   "POST /api/stock-transfers/{id}/cancel",
+				},
+				"promotions": map[string]string{
+					"list":    "GET /api/promotions?include_archived=true",
+					"create":  "POST /api/promotions",
+					"detail":  "GET /api/promotions/{id}",
+					"update":  "PUT /api/promotions/{id}",
+					"delete":  "DELETE /api/promotions/{id}",
+					"restore": "POST /api/promotions/{id}/restore",
+				},
+				"vouchers": map[string]string{
+					"list":    "GET /api/vouchers?include_archived=true",
+					"create":  "POST /api/vouchers",
+					"detail":  "GET /api/vouchers/{id}",
+					"update":  "PUT /api/vouchers/{id}",
+					"delete":  "DELETE /api/vouchers/{id}",
+					"restore": "POST /api/vouchers/{id}/restore",
 				},
 				"transactions": map[string]string{
 					"checkout": "POST /api/checkout",
+					"price":    "POST /api/checkout/price",
+					"list":     "GET /api/transactions?page={page}&limit={limit}&start_date={YYYY-MM-DD}&end_date={YYYY-MM-DD}&min_total={amount}&max_total={amount}&product_id={id}&outlet_id={id}",
+					"detail":   "GET /api/transactions/{id}",
+					"void":     "POST /api/transactions/{id}/void",
+					"refund":   "POST /api/transactions/{id}/refunds",
 				},
 				"reports": map[string]string{
-					"today":      "GET /api/report/hari-ini",
-					"date_range": "GET /api/report?start_date={YYYY-MM-DD}&end_date={YYYY-MM-DD}",
+					"today":      "GET /api/report/hari-ini?outlet_id={id}",
+					"date_range": "GET /api/report?start_date={YYYY-MM-DD}&end_date={YYYY-MM-DD}&outlet_id={id}",
+					"reorder":    "GET /api/report/reorder?days={days}&cover_days={days}&outlet_id={id}",
+					"expiring":   "GET /api/report/expiring-batches?days={days}&outlet_id={id}",
+					"vouchers":   "GET /api/report/vouchers?start_date={YYYY-MM-DD}&end_date={YYYY-MM-DD}&outlet_id={id}",
 				},
 			},
 		})
@@ -86,7 +246,9 @@ func main(){
 	// POST localhost:8080/api/produk
 	// GET localhost:8080/api/produk
 	productRepo := repositories.NewProductRepository(db)
-	productService := services.NewProductService(productRepo)
+	categoryRepo := repositories.NewCategoryRepository(db)
+	stockRepo := repositories.NewStockRepository(db)
+	productService := services.NewProductService(productRepo, categoryRepo, stockRepo, scaleFormats, taxRates)
 	productHandler := handlers.NewProductHandler(productService)
 
 	http.HandleFunc("/api/produk", productHandler.HandleProducts)
@@ -95,21 +257,102 @@ func main(){
 	// Category routes dengan layered architecture
 	// GET/POST localhost:8080/api/categories
 	// GET/PUT/DELETE localhost:8080/api/categories/{id}
-	categoryRepo := repositories.NewCategoryRepository(db)
-	categoryService := services.NewCategoryService(categoryRepo)
+	// GET localhost:8080/api/categories/{id}/products
+	categoryService := services.NewCategoryService(categoryRepo, productRepo)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
 
 	http.HandleFunc("/api/categories", categoryHandler.HandleCategories)
 	http.HandleFunc("/api/categories/", categoryHandler.HandleCategoryByID)
 
+	// Outlet (cabang toko), stok dan penjualan dicatat per outlet
+	// GET/POST localhost:8080/api/outlets
+	// GET/PUT/DELETE localhost:8080/api/outlets/{id}
+	// POST localhost:8080/api/outlets/{id}/restore
+	outletRepo := repositories.NewOutletRepository(db)
+	outletService := services.NewOutletService(outletRepo)
+	outletHandler := handlers.NewOutletHandler(outletService)
+
+	http.HandleFunc("/api/outlets", outletHandler.HandleOutlets)
+	http.HandleFunc("/api/outlets/", outletHandler.HandleOutletByID)
+
+	// Supplier & purchase order
+	// GET/POST localhost:8080/api/suppliers
+	// GET/PUT/DELETE localhost:8080/api/suppliers/{id}
+	// GET/POST localhost:8080/api/purchase-orders
+	// GET/PUT localhost:8080/api/purchase-orders/{id}
+	// POST localhost:8080/api/purchase-orders/{id}/order, /cancel, /receipts
+	supplierRepo := repositories.NewSupplierRepository(db)
+	supplierService := services.NewSupplierService(supplierRepo)
+	supplierHandler := handlers.NewSupplierHandler(supplierService)
+
+	http.HandleFunc("/api/suppliers", supplierHandler.HandleSuppliers)
+	http.HandleFunc("/api/suppliers/", supplierHandler.HandleSupplierByID)
+
+	purchaseOrderRepo := repositories.NewPurchaseOrderRepository(db)
+	purchaseOrderService := services.NewPurchaseOrderService(purchaseOrderRepo, supplierRepo)
+	purchaseOrderHandler := handlers.NewPurchaseOrderHandler(purchaseOrderService)
+
+	http.HandleFunc("/api/purchase-orders", purchaseOrderHandler.HandlePurchaseOrders)
+	http.HandleFunc("/api/purchase-orders/", purchaseOrderHandler.HandlePurchaseOrderByID)
+
+	// Stock opname (hitung fisik)
+	// GET/POST localhost:8080/api/stock-opnames
+	// GET localhost:8080/api/stock-opnames/{id}
+	// POST localhost:8080/api/stock-opnames/{id}/counts, /post, /cancel
+	stockOpnameRepo := repositories.NewStockOpnameRepository(db)
+	stockOpnameService := services.NewStockOpnameService(stockOpnameRepo, categoryRepo)
+	stockOpnameHandler := handlers.NewStockOpnameHandler(stockOpnameService)
+
+	http.HandleFunc("/api/stock-opnames", stockOpnameHandler.HandleStockOpnames)
+	http.HandleFunc("/api/stock-opnames/", stockOpnameHandler.HandleStockOpnameByID)
+
+	// Transfer stok antar outlet
+	// GET/POST localhost:8080/api/stock-transfers
+	// GET localhost:8080/api/stock-transfers/{id}
+	// POST localhost:8080/api/stock-transfers/{id}/dispatch, /receive, /cancel
+	stockTransferRepo := repositories.NewStockTransferRepository(db)
+	stockTransferService := services.NewStockTransferService(stockTransferRepo)
+	stockTransferHandler := handlers.NewStockTransferHandler(stockTransferService)
+
+	http.HandleFunc("/api/stock-transfers", stockTransferHandler.HandleStockTransfers)
+	http.HandleFunc("/api/stock-transfers/", stockTransferHandler.HandleStockTransferByID)
+
+	// Promo otomatis yang dievaluasi saat checkout
+	// GET/POST localhost:8080/api/promotions
+	// GET/PUT/DELETE localhost:8080/api/promotions/{id}
+	// POST localhost:8080/api/promotions/{id}/restore
+	promotionRepo := repositories.NewPromotionRepository(db)
+	promotionService := services.NewPromotionService(promotionRepo)
+	promotionHandler := handlers.NewPromotionHandler(promotionService)
+
+	http.HandleFunc("/api/promotions", promotionHandler.HandlePromotions)
+	http.HandleFunc("/api/promotions/", promotionHandler.HandlePromotionByID)
+
+	// Voucher yang ditukarkan pelanggan saat checkout
+	// GET/POST localhost:8080/api/vouchers
+	// GET/PUT/DELETE localhost:8080/api/vouchers/{id}
+	// POST localhost:8080/api/vouchers/{id}/restore
+	voucherRepo := repositories.NewVoucherRepository(db)
+	voucherService := services.NewVoucherService(voucherRepo)
+	voucherHandler := handlers.NewVoucherHandler(voucherService)
+
+	http.HandleFunc("/api/vouchers", voucherHandler.HandleVouchers)
+	http.HandleFunc("/api/vouchers/", voucherHandler.HandleVoucherByID)
+
 	// Transaction
 	transactionRepo := repositories.NewTransactionRepository(db)
-	transactionService := services.NewTransactionService(transactionRepo)
-	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	transactionService := services.NewTransactionService(transactionRepo, productService, config.IdempotencyKeyTTL, discountLimits, taxConfig, cashRounding)
+	transactionHandler := handlers.NewTransactionHandler(transactionService, config.RoleHeader)
 
 	http.HandleFunc("/api/checkout", transactionHandler.HandleCheckout) // POST
+	http.HandleFunc("/api/checkout/price", transactionHandler.HandlePriceCart) // POST hitung harga cart tanpa checkout
+	http.HandleFunc("/api/transactions", transactionHandler.HandleTransactions) // GET list
+	http.HandleFunc("/api/transactions/", transactionHandler.HandleTransactionByID) // GET detail, POST void/refunds
 	http.HandleFunc("/api/report/hari-ini", transactionHandler.HandleSalesReport) // GET
 	http.HandleFunc("/api/report", transactionHandler.HandleReportByDateRange) // GET with date range
+	http.HandleFunc("/api/report/reorder", productHandler.HandleReorderReport) // GET saran order ulang
+	http.HandleFunc("/api/report/expiring-batches", productHandler.HandleExpiringReport) // GET batch yang akan kadaluarsa
+	http.HandleFunc("/api/report/vouchers", voucherHandler.HandleUsageReport) // GET penukaran voucher
 
 	// Serve Swagger UI documentation
 	http.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
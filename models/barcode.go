@@ -0,0 +1,148 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidBarcode   = errors.New("barcode harus EAN-8, UPC-A atau EAN-13 dengan check digit yang valid")
+	ErrDuplicateBarcode = errors.New("barcode sudah dipakai produk lain")
+	ErrDuplicateSKU     = errors.New("sku sudah dipakai produk lain")
+	ErrDuplicatePLU     = errors.New("plu sudah dipakai produk lain")
+	// ErrInvalidScaleLabel - label timbangan tidak bisa dijadikan quantity (berat/harga 0 atau produk tanpa harga)
+	ErrInvalidScaleLabel = errors.New("label timbangan tidak valid")
+)
+
+// IsValidBarcode - cek format EAN-8 (8 digit), UPC-A (12 digit) atau EAN-13 (13 digit)
+// beserta check digit-nya (mod 10, bobot 3/1 dari kanan).
+func IsValidBarcode(code string) bool {
+	switch len(code) {
+	case 8, 12, 13:
+	default:
+		return false
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return checkDigit(code[:len(code)-1]) == int(code[len(code)-1]-'0')
+}
+
+// checkDigit - hitung check digit GTIN untuk digit data (tanpa check digit)
+func checkDigit(data string) int {
+	sum := 0
+	weight := 3
+	for i := len(data) - 1; i >= 0; i-- {
+		sum += int(data[i]-'0') * weight
+		weight = 4 - weight
+	}
+	return (10 - sum%10) % 10
+}
+
+// Jenis nilai yang ditanam di barcode timbangan
+const (
+	ScaleValueWeight = "weight"
+	ScaleValuePrice  = "price"
+)
+
+// ScaleBarcodeFormat - format label timbangan EAN-13 in-store:
+// prefix (2 digit) + PLU (PLULength digit) + nilai (sisa digit) + check digit.
+// Untuk weight, nilai dibagi 10^Decimals (mis. gram -> kg dengan Decimals 3).
+type ScaleBarcodeFormat struct {
+	Prefix    string
+	PLULength int
+	ValueType string
+	Decimals  int
+}
+
+// ScaleBarcode - hasil decode label timbangan, ValueType menentukan Weight atau Price yang terisi
+type ScaleBarcode struct {
+	PLU       string
+	ValueType string
+	Weight    float64
+	Price     int
+}
+
+// BarcodeLookup - hasil scan. Untuk label timbangan, Quantity (dan Subtotal kalau label
+// berisi harga) diisi dari barcode; untuk barcode biasa hanya produknya.
+type BarcodeLookup struct {
+	Product
+	Quantity *float64 `json:"scanned_quantity,omitempty"`
+	Subtotal *int     `json:"scanned_subtotal,omitempty"`
+}
+
+// ParseScaleBarcodeFormats - parse konfigurasi "prefix:plu_length:weight|price:decimals",
+// dipisah koma. Contoh: "20:5:weight:3,21:5:price:0"
+func ParseScaleBarcodeFormats(config string) ([]ScaleBarcodeFormat, error) {
+	formats := make([]ScaleBarcodeFormat, 0)
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("scale barcode format %q must be prefix:plu_length:weight|price:decimals", entry)
+		}
+
+		f := ScaleBarcodeFormat{Prefix: parts[0], ValueType: parts[2]}
+		if len(f.Prefix) != 2 || f.Prefix < "20" || f.Prefix > "29" {
+			return nil, fmt.Errorf("scale barcode prefix %q must be between 20 and 29", f.Prefix)
+		}
+
+		var err error
+		f.PLULength, err = strconv.Atoi(parts[1])
+		if err != nil || f.PLULength < 1 || f.PLULength > 9 {
+			return nil, fmt.Errorf("scale barcode plu length %q must be between 1 and 9", parts[1])
+		}
+		if f.ValueType != ScaleValueWeight && f.ValueType != ScaleValuePrice {
+			return nil, fmt.Errorf("scale barcode value type %q must be weight or price", f.ValueType)
+		}
+		f.Decimals, err = strconv.Atoi(parts[3])
+		if err != nil || f.Decimals < 0 || f.Decimals > 3 {
+			return nil, fmt.Errorf("scale barcode decimals %q must be between 0 and 3", parts[3])
+		}
+
+		formats = append(formats, f)
+	}
+
+	return formats, nil
+}
+
+// DecodeScaleBarcode - decode barcode timbangan sesuai format yang cocok dengan prefix-nya.
+// ok false kalau barcode bukan label timbangan yang dikenal.
+func DecodeScaleBarcode(code string, formats []ScaleBarcodeFormat) (*ScaleBarcode, bool) {
+	if len(code) != 13 || !IsValidBarcode(code) {
+		return nil, false
+	}
+
+	for _, f := range formats {
+		if !strings.HasPrefix(code, f.Prefix) {
+			continue
+		}
+
+		plu := code[2 : 2+f.PLULength]
+		value, err := strconv.Atoi(code[2+f.PLULength : 12])
+		if err != nil {
+			return nil, false
+		}
+
+		result := &ScaleBarcode{PLU: strings.TrimLeft(plu, "0"), ValueType: f.ValueType}
+		if f.ValueType == ScaleValueWeight {
+			result.Weight = float64(value) / math.Pow10(f.Decimals)
+		} else {
+			result.Price = value / int(math.Pow10(f.Decimals))
+		}
+		return result, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrBatchNotFound = errors.New("batch not found")
+	// ErrBatchRequired - stok masuk untuk produk batch-tracked wajib menyebut batch_number
+	ErrBatchRequired = errors.New("batch_number is required for batch-tracked products")
+)
+
+// DefaultBatchNumber - batch penampung untuk stok batch-tracked yang masuk tanpa nomor batch
+// (stok awal, surplus opname, atau stok lama saat produk baru diberi flag batch_tracked)
+const DefaultBatchNumber = "DEFAULT"
+
+// ProductBatch - stok per batch/lot di satu outlet. ExpiryDate format YYYY-MM-DD, nil kalau tidak ada kadaluarsa.
+// Jumlah Quantity semua batch sebuah produk di satu outlet selalu sama dengan stok produk di outlet itu.
+type ProductBatch struct {
+	ID          int       `json:"id"`
+	ProductID   int       `json:"product_id"`
+	OutletID    int       `json:"outlet_id"`
+	BatchNumber string    `json:"batch_number"`
+	ExpiryDate  *string   `json:"expiry_date"`
+	Quantity    float64   `json:"quantity"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BatchAllocation - berapa quantity yang diambil dari (negatif) atau masuk ke (positif) satu batch
+type BatchAllocation struct {
+	BatchID     int     `json:"batch_id"`
+	BatchNumber string  `json:"batch_number"`
+	ExpiryDate  *string `json:"expiry_date"`
+	Quantity    float64 `json:"quantity"`
+}
+
+// ExpiringBatch - baris laporan batch yang akan (atau sudah) kadaluarsa.
+// DaysLeft negatif berarti sudah lewat, Value adalah quantity x harga pokok.
+type ExpiringBatch struct {
+	ProductBatch
+	ProductName string `json:"product_name"`
+	SKU         string `json:"sku,omitempty"`
+	OutletName  string `json:"outlet_name"`
+	DaysLeft    int    `json:"days_left"`
+	Value       int    `json:"value"`
+}
+
+// ExpiringBatchReport - OutletID nil berarti semua outlet
+type ExpiringBatchReport struct {
+	Days       int             `json:"days"`
+	OutletID   *int            `json:"outlet_id"`
+	TotalValue int             `json:"total_value"`
+	Items      []ExpiringBatch `json:"items"`
+}
+
+// ParseExpiryDate - validasi tanggal kadaluarsa YYYY-MM-DD, kosong berarti tanpa kadaluarsa
+func ParseExpiryDate(value *string) (*string, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	if _, err := time.Parse("2006-01-02", *value); err != nil {
+		return nil, errors.New("expiry_date must use YYYY-MM-DD format")
+	}
+	return value, nil
+}
+
+// AllocateFEFO - ambil quantity dari batches yang sudah urut first-expired-first-out.
+// Mengembalikan alokasi (quantity positif), sisa batches, dan kekurangan kalau batches tidak cukup.
+func AllocateFEFO(batches []BatchAllocation, quantity float64) ([]BatchAllocation, []BatchAllocation, float64) {
+	allocations := make([]BatchAllocation, 0)
+	rest := make([]BatchAllocation, 0, len(batches))
+	for _, b := range batches {
+		if quantity > 0 && b.Quantity > 0 {
+			take := math.Min(b.Quantity, quantity)
+			quantity = RoundQuantity(quantity - take)
+			b.Quantity = RoundQuantity(b.Quantity - take)
+			allocated := b
+			allocated.Quantity = take
+			allocations = append(allocations, allocated)
+		}
+		if b.Quantity > 0 {
+			rest = append(rest, b)
+		}
+	}
+	return allocations, rest, quantity
+}
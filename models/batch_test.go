@@ -0,0 +1,43 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllocateFEFO(t *testing.T) {
+	batch := func(id int, quantity float64) BatchAllocation {
+		return BatchAllocation{BatchID: id, Quantity: quantity}
+	}
+	batches := []BatchAllocation{batch(1, 2.5), batch(2, 0), batch(3, 4)}
+
+	tests := []struct {
+		name          string
+		batches       []BatchAllocation
+		quantity      float64
+		wantAllocated []BatchAllocation
+		wantRest      []BatchAllocation
+		wantShortfall float64
+	}{
+		{"first expiring batch first", batches, 3, []BatchAllocation{batch(1, 2.5), batch(3, 0.5)}, []BatchAllocation{batch(3, 3.5)}, 0},
+		{"exact amount", batches, 6.5, []BatchAllocation{batch(1, 2.5), batch(3, 4)}, []BatchAllocation{}, 0},
+		{"not enough stock", batches, 10, []BatchAllocation{batch(1, 2.5), batch(3, 4)}, []BatchAllocation{}, 3.5},
+		{"nothing requested", batches, 0, []BatchAllocation{}, []BatchAllocation{batch(1, 2.5), batch(3, 4)}, 0},
+		{"decimal quantities", []BatchAllocation{batch(1, 0.3), batch(2, 0.3)}, 0.6, []BatchAllocation{batch(1, 0.3), batch(2, 0.3)}, []BatchAllocation{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocated, rest, shortfall := AllocateFEFO(tt.batches, tt.quantity)
+			if !reflect.DeepEqual(allocated, tt.wantAllocated) {
+				t.Errorf("allocated = %v, want %v", allocated, tt.wantAllocated)
+			}
+			if !reflect.DeepEqual(rest, tt.wantRest) {
+				t.Errorf("rest = %v, want %v", rest, tt.wantRest)
+			}
+			if shortfall != tt.wantShortfall {
+				t.Errorf("shortfall = %g, want %g", shortfall, tt.wantShortfall)
+			}
+		})
+	}
+}
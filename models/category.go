@@ -1,7 +1,23 @@
 package models
 
+import (
+	"errors"
+	"time"
+)
+
+// Category - ArchivedAt terisi kalau category sudah diarsipkan (soft delete)
 type Category struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
 }
+
+var (
+	ErrCategoryNotFound = errors.New("category tidak ditemukan")
+	// ErrCategoryInUse - category masih dipakai produk dan tidak ada kategori tujuan reassign
+	ErrCategoryInUse = errors.New("category masih dipakai oleh produk")
+	// ErrInvalidReassignTarget - category tujuan reassign tidak ada atau sama dengan yang dihapus
+	ErrInvalidReassignTarget = errors.New("category tujuan reassign tidak valid")
+	ErrCategoryArchived      = errors.New("category sudah diarsipkan")
+)
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Jenis diskon: persen dari nilai yang didiskon atau potongan rupiah tetap
+const (
+	DiscountTypePercent = "percent"
+	DiscountTypeFixed   = "fixed"
+)
+
+// DefaultDiscountRole - role yang dipakai kalau checkout tidak menyebut role
+const DefaultDiscountRole = "cashier"
+
+var (
+	// ErrInvalidDiscount - diskon melebihi nilai line/total yang didiskon
+	ErrInvalidDiscount = errors.New("invalid discount")
+	// ErrDiscountLimitExceeded - total diskon melebihi batas role yang melakukan checkout
+	ErrDiscountLimitExceeded = errors.New("discount exceeds the maximum allowed for the role")
+	// ErrUnknownDiscountRole - role checkout tidak ada di konfigurasi DISCOUNT_LIMITS
+	ErrUnknownDiscountRole = errors.New("unknown role")
+)
+
+// Discount - diskon per line (CheckoutItem) atau per keranjang (CheckoutRequest).
+// Value adalah persen (0-100) untuk percent, rupiah untuk fixed.
+type Discount struct {
+	Type  string  `json:"type"`
+	Value float64 `json:"value"`
+}
+
+// Validate - cek jenis dan rentang nilai, tanpa melihat nilai yang didiskon
+func (d *Discount) Validate() error {
+	switch d.Type {
+	case DiscountTypePercent:
+		if d.Value < 0 || d.Value > 100 {
+			return errors.New("percent discount value must be between 0 and 100")
+		}
+	case DiscountTypeFixed:
+		if d.Value < 0 || d.Value != math.Trunc(d.Value) {
+			return errors.New("fixed discount value must be a whole rupiah amount of at least 0")
+		}
+	default:
+		return fmt.Errorf("discount type must be %s or %s", DiscountTypePercent, DiscountTypeFixed)
+	}
+	return nil
+}
+
+// Amount - potongan rupiah untuk base. Persen dibulatkan ke rupiah terdekat,
+// potongan tetap yang melebihi base ditolak.
+func (d *Discount) Amount(base int) (int, error) {
+	if d == nil {
+		return 0, nil
+	}
+	if d.Type == DiscountTypePercent {
+		return int(math.Round(float64(base) * d.Value / 100)), nil
+	}
+	amount := int(d.Value)
+	if amount > base {
+		return 0, fmt.Errorf("%w: fixed discount %d exceeds the amount %d", ErrInvalidDiscount, amount, base)
+	}
+	return amount, nil
+}
+
+// AllocateDiscount - bagi diskon keranjang ke setiap line sebanding nilainya (largest remainder).
+// Setiap bagian dibulatkan ke bawah, lalu sisa rupiahnya dibagikan satu per satu ke line dengan
+// pecahan terbesar, jadi jumlahnya tepat sama dengan discount dan tidak ada bagian yang negatif.
+func AllocateDiscount(discount int, amounts []int) []int {
+	shares := make([]int, len(amounts))
+	total := 0
+	for _, a := range amounts {
+		if a > 0 {
+			total += a
+		}
+	}
+	if discount == 0 || total == 0 {
+		return shares
+	}
+
+	remainders := make([]int64, len(amounts))
+	order := make([]int, 0, len(amounts))
+	allocated := 0
+	for i, a := range amounts {
+		if a <= 0 {
+			continue
+		}
+		product := int64(discount) * int64(a)
+		shares[i] = int(product / int64(total))
+		remainders[i] = product % int64(total)
+		allocated += shares[i]
+		order = append(order, i)
+	}
+
+	// Pecahan sama besar dibagi ke line yang lebih dulu
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for k := 0; k < discount-allocated; k++ {
+		shares[order[k]]++
+	}
+	return shares
+}
+
+// DiscountLimits - persen diskon maksimum (dari nilai kotor transaksi) per role
+type DiscountLimits map[string]float64
+
+// ParseDiscountLimits - parse konfigurasi "role:max_percent", dipisah koma.
+// Contoh: "cashier:10,supervisor:25,manager:100"
+func ParseDiscountLimits(config string) (DiscountLimits, error) {
+	limits := make(DiscountLimits)
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("discount limit %q must be role:max_percent", entry)
+		}
+		percent, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("discount limit %q must be between 0 and 100", parts[1])
+		}
+		limits[strings.TrimSpace(parts[0])] = percent
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllocateDiscount(t *testing.T) {
+	tests := []struct {
+		name     string
+		discount int
+		amounts  []int
+		want     []int
+	}{
+		{"proportional", 1000, []int{6000, 4000}, []int{600, 400}},
+		{"equal remainders go to the first lines", 100, []int{300, 300, 300}, []int{34, 33, 33}},
+		{"no negative share for a small last line", 15, []int{5, 5, 5, 5, 1}, []int{4, 4, 3, 3, 1}},
+		{"zero amount lines get nothing", 500, []int{0, 1000, 0, 1000}, []int{0, 250, 0, 250}},
+		{"zero discount", 0, []int{1000, 2000}, []int{0, 0}},
+		{"zero total", 500, []int{0, 0}, []int{0, 0}},
+		{"full amount", 3333, []int{1111, 1111, 1111}, []int{1111, 1111, 1111}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AllocateDiscount(tt.discount, tt.amounts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("AllocateDiscount(%d, %v) = %v, want %v", tt.discount, tt.amounts, got, tt.want)
+			}
+			sum := 0
+			for i, share := range got {
+				if share < 0 || (tt.amounts[i] > 0 && share > tt.amounts[i]) {
+					t.Errorf("share %d = %d is outside 0..%d", i, share, tt.amounts[i])
+				}
+				sum += share
+			}
+			if sumAmounts(tt.amounts) > 0 && sum != tt.discount {
+				t.Errorf("shares sum to %d, want %d", sum, tt.discount)
+			}
+		})
+	}
+}
+
+func sumAmounts(amounts []int) int {
+	sum := 0
+	for _, a := range amounts {
+		sum += a
+	}
+	return sum
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Outlet - toko/gudang yang punya stok sendiri. ArchivedAt terisi kalau sudah diarsipkan (soft delete).
+type Outlet struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Address    string     `json:"address"`
+	Phone      string     `json:"phone"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+// OutletStock - stok satu produk di satu outlet. InTransit adalah quantity transfer yang sudah dikirim
+// ke outlet ini tapi belum diterima, belum termasuk Stock.
+type OutletStock struct {
+	OutletID   int     `json:"outlet_id"`
+	OutletName string  `json:"outlet_name"`
+	Stock      float64 `json:"stock"`
+	InTransit  float64 `json:"in_transit"`
+}
+
+var (
+	ErrOutletNotFound = errors.New("outlet tidak ditemukan")
+	ErrOutletArchived = errors.New("outlet sudah diarsipkan")
+)
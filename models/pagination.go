@@ -0,0 +1,17 @@
+package models
+
+// Pagination - metadata halaman untuk response list
+type Pagination struct {
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+func NewPagination(page, limit, total int) Pagination {
+	totalPages := 0
+	if limit > 0 {
+		totalPages = (total + limit - 1) / limit
+	}
+	return Pagination{Page: page, Limit: limit, Total: total, TotalPages: totalPages}
+}
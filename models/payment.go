@@ -0,0 +1,173 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Metode pembayaran yang diterima di checkout
+const (
+	PaymentMethodCash      = "cash"
+	PaymentMethodDebitCard = "debit_card"
+	PaymentMethodQRIS      = "qris"
+	PaymentMethodEWallet   = "e_wallet"
+	PaymentMethodTransfer  = "transfer"
+)
+
+var PaymentMethods = []string{
+	PaymentMethodCash,
+	PaymentMethodDebitCard,
+	PaymentMethodQRIS,
+	PaymentMethodEWallet,
+	PaymentMethodTransfer,
+}
+
+func IsValidPaymentMethod(method string) bool {
+	for _, m := range PaymentMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidPayment - pembayaran tidak menutup total atau non-tunai melebihi total
+var ErrInvalidPayment = errors.New("invalid payment")
+
+// PaymentInput - satu pembayaran dari CheckoutRequest. Untuk cash, Amount adalah uang yang diterima.
+type PaymentInput struct {
+	Method    string `json:"method"`
+	Amount    int    `json:"amount"`
+	Reference string `json:"reference,omitempty"`
+}
+
+// Payment - pembayaran yang tersimpan. Amount adalah nilai yang dipakai untuk membayar transaksi,
+// Tendered uang yang diterima dan Change kembalian (hanya untuk cash).
+type Payment struct {
+	ID            int    `json:"id"`
+	TransactionID int    `json:"transaction_id"`
+	Method        string `json:"method"`
+	Amount        int    `json:"amount"`
+	Tendered      int    `json:"tendered"`
+	Change        int    `json:"change"`
+	Reference     string `json:"reference,omitempty"`
+}
+
+// CashRounding - pembulatan bagian tagihan yang dibayar tunai ke kelipatan Unit rupiah (misal 100 atau 500)
+// karena uang koin kecil sudah jarang. Unit 0 atau 1 berarti tanpa pembulatan.
+type CashRounding struct {
+	Mode string
+	Unit int
+}
+
+// Round - selisih pembulatan (bisa negatif) untuk tagihan tunai amount
+func (r CashRounding) Round(amount int) int {
+	if r.Unit <= 1 || amount <= 0 {
+		return 0
+	}
+	return RoundAmount(float64(amount)/float64(r.Unit), r.Mode)*r.Unit - amount
+}
+
+// Adjustment - selisih pembulatan untuk total kalau dibayar dengan inputs. Hanya sisa tagihan
+// yang ditutup cash yang dibulatkan; tanpa pembayaran cash tidak ada pembulatan.
+func (r CashRounding) Adjustment(total int, inputs []PaymentInput) int {
+	nonCash, hasCash := 0, false
+	for _, input := range inputs {
+		if input.Method == PaymentMethodCash {
+			hasCash = true
+		} else {
+			nonCash += input.Amount
+		}
+	}
+	if !hasCash {
+		return 0
+	}
+	return r.Round(total - nonCash)
+}
+
+// AllocatePayments - cocokkan pembayaran dengan total. Non-tunai tidak boleh melebihi total
+// karena tidak bisa diberi kembalian; sisa total harus ditutup cash dan kelebihannya jadi kembalian.
+func AllocatePayments(total int, inputs []PaymentInput) ([]Payment, int, error) {
+	nonCash, cash := 0, 0
+	for _, input := range inputs {
+		if input.Method == PaymentMethodCash {
+			cash += input.Amount
+		} else {
+			nonCash += input.Amount
+		}
+	}
+
+	if nonCash > total {
+		return nil, 0, fmt.Errorf("%w: non-cash payments (%d) exceed total amount (%d)", ErrInvalidPayment, nonCash, total)
+	}
+	if nonCash+cash < total {
+		return nil, 0, fmt.Errorf("%w: payments (%d) do not cover total amount (%d)", ErrInvalidPayment, nonCash+cash, total)
+	}
+
+	change := nonCash + cash - total
+	payments := make([]Payment, 0, len(inputs))
+	for _, input := range inputs {
+		payments = append(payments, Payment{
+			Method:    input.Method,
+			Amount:    input.Amount,
+			Tendered:  input.Amount,
+			Reference: input.Reference,
+		})
+	}
+
+	// Kembalian diambil dari pembayaran cash, mulai dari yang terakhir
+	remaining := change
+	for i := len(payments) - 1; i >= 0 && remaining > 0; i-- {
+		if payments[i].Method != PaymentMethodCash {
+			continue
+		}
+		taken := remaining
+		if taken > payments[i].Amount {
+			taken = payments[i].Amount
+		}
+		payments[i].Amount -= taken
+		payments[i].Change = taken
+		remaining -= taken
+	}
+
+	return payments, change, nil
+}
+
+// RefundPayment - bagian refund yang dikembalikan lewat satu metode pembayaran
+type RefundPayment struct {
+	Method string `json:"method"`
+	Amount int    `json:"amount"`
+}
+
+// AllocateRefund - kembalikan amount ke metode pembayaran asal transaksi. refundable adalah sisa yang masih
+// bisa dikembalikan per metode, urut dari metode yang dibayar terakhir. Kelebihan yang tidak tertampung
+// (misal karena pembulatan tunai ke bawah) dikembalikan tunai.
+func AllocateRefund(amount int, refundable []RefundPayment) []RefundPayment {
+	payments := make([]RefundPayment, 0, len(refundable))
+	remaining := amount
+	for _, r := range refundable {
+		if remaining <= 0 {
+			break
+		}
+		taken := remaining
+		if taken > r.Amount {
+			taken = r.Amount
+		}
+		if taken <= 0 {
+			continue
+		}
+		payments = append(payments, RefundPayment{Method: r.Method, Amount: taken})
+		remaining -= taken
+	}
+
+	if remaining > 0 {
+		for i := range payments {
+			if payments[i].Method == PaymentMethodCash {
+				payments[i].Amount += remaining
+				return payments
+			}
+		}
+		payments = append(payments, RefundPayment{Method: PaymentMethodCash, Amount: remaining})
+	}
+	return payments
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllocateRefund(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     int
+		refundable []RefundPayment
+		want       []RefundPayment
+	}{
+		{
+			"single method",
+			10000,
+			[]RefundPayment{{PaymentMethodQRIS, 25000}},
+			[]RefundPayment{{PaymentMethodQRIS, 10000}},
+		},
+		{
+			"last paid method first",
+			30000,
+			[]RefundPayment{{PaymentMethodCash, 20000}, {PaymentMethodQRIS, 20000}},
+			[]RefundPayment{{PaymentMethodCash, 20000}, {PaymentMethodQRIS, 10000}},
+		},
+		{
+			"already refunded method is skipped",
+			5000,
+			[]RefundPayment{{PaymentMethodCash, 0}, {PaymentMethodQRIS, 20000}},
+			[]RefundPayment{{PaymentMethodQRIS, 5000}},
+		},
+		{
+			"cash rounded down is topped up in cash",
+			11655,
+			[]RefundPayment{{PaymentMethodCash, 11600}},
+			[]RefundPayment{{PaymentMethodCash, 11655}},
+		},
+		{
+			"no payments recorded goes back in cash",
+			8000,
+			nil,
+			[]RefundPayment{{PaymentMethodCash, 8000}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AllocateRefund(tt.amount, tt.refundable)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("AllocateRefund(%d, %v) = %v, want %v", tt.amount, tt.refundable, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCashRoundingAdjustment(t *testing.T) {
+	cash := func(amount int) PaymentInput { return PaymentInput{Method: PaymentMethodCash, Amount: amount} }
+	qris := func(amount int) PaymentInput { return PaymentInput{Method: PaymentMethodQRIS, Amount: amount} }
+
+	tests := []struct {
+		name     string
+		rounding CashRounding
+		total    int
+		inputs   []PaymentInput
+		want     int
+	}{
+		{"disabled", CashRounding{Mode: RoundingNearest, Unit: 0}, 11655, []PaymentInput{cash(20000)}, 0},
+		{"nearest up", CashRounding{Mode: RoundingNearest, Unit: 100}, 11655, []PaymentInput{cash(20000)}, 45},
+		{"nearest down", CashRounding{Mode: RoundingNearest, Unit: 100}, 11649, []PaymentInput{cash(20000)}, -49},
+		{"down to 500", CashRounding{Mode: RoundingDown, Unit: 500}, 11655, []PaymentInput{cash(20000)}, -155},
+		{"up to 500", CashRounding{Mode: RoundingUp, Unit: 500}, 11655, []PaymentInput{cash(20000)}, 345},
+		{"already a multiple", CashRounding{Mode: RoundingUp, Unit: 500}, 11500, []PaymentInput{cash(20000)}, 0},
+		{"non-cash only", CashRounding{Mode: RoundingNearest, Unit: 100}, 11655, []PaymentInput{qris(11655)}, 0},
+		{"only the cash part is rounded", CashRounding{Mode: RoundingNearest, Unit: 100}, 11655, []PaymentInput{qris(5000), cash(7000)}, 45},
+		{"non-cash covers the total", CashRounding{Mode: RoundingNearest, Unit: 100}, 11655, []PaymentInput{qris(11655), cash(1000)}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rounding.Adjustment(tt.total, tt.inputs); got != tt.want {
+				t.Errorf("Adjustment(%d) = %d, want %d", tt.total, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,94 @@
 package models
 
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Product - Price adalah harga jual per Unit, CostPrice harga pokok (modal) per Unit.
+// Stock adalah total semua outlet dan boleh desimal untuk unit timbangan (kg, liter),
+// rinciannya ada di OutletStocks (hanya diisi di detail produk).
+// MinStock adalah reorder point (0 berarti tidak dipantau), ReorderQty jumlah order standar.
+// BatchTracked berarti stok dicatat per batch dengan tanggal kadaluarsa dan penjualan memotong FEFO.
+// TaxCategory menentukan tarif pajak dari konfigurasi TAX_RATES (kosong berarti DefaultTaxCategory).
 type Product struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Price int    `json:"price"`
-	Stock int    `json:"stock"`
-}
\ No newline at end of file
+	ID           int           `json:"id"`
+	SKU          string        `json:"sku"`
+	PLU          string        `json:"plu,omitempty"`
+	Barcodes     []string      `json:"barcodes"`
+	Name         string        `json:"name"`
+	Unit         string        `json:"unit"`
+	Price        int           `json:"price"`
+	CostPrice    int           `json:"cost_price"`
+	Stock        float64       `json:"stock"`
+	MinStock     float64       `json:"min_stock"`
+	ReorderQty   float64       `json:"reorder_qty"`
+	BatchTracked bool          `json:"batch_tracked"`
+	TaxCategory  string        `json:"tax_category"`
+	CategoryID   *int          `json:"category_id"`
+	Category     *Category     `json:"category,omitempty"`
+	ArchivedAt   *time.Time    `json:"archived_at,omitempty"`
+	OutletStocks []OutletStock `json:"outlet_stocks,omitempty"`
+}
+
+// Satuan produk. Hanya UnitPcs yang wajib quantity bulat.
+const (
+	UnitPcs   = "pcs"
+	UnitKg    = "kg"
+	UnitGram  = "gram"
+	UnitLiter = "liter"
+)
+
+var Units = []string{UnitPcs, UnitKg, UnitGram, UnitLiter}
+
+func IsValidUnit(unit string) bool {
+	for _, u := range Units {
+		if u == unit {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundQuantity - quantity dan stok disimpan dengan 3 angka di belakang koma
+func RoundQuantity(q float64) float64 {
+	return math.Round(q*1000) / 1000
+}
+
+// ProductFilter - filter untuk listing produk. Field kosong berarti tidak difilter,
+// Limit 0 berarti tanpa pagination. Produk yang diarsipkan hanya ikut kalau IncludeArchived.
+// LowStock hanya mengambil produk dengan min_stock > 0 dan stock <= min_stock.
+// OutletID 0 berarti stok total semua outlet; kalau diisi InStock, LowStock, sort dan Stock memakai stok outlet itu.
+type ProductFilter struct {
+	Name            string
+	CategoryID      int
+	OutletID        int
+	MinPrice        *int
+	MaxPrice        *int
+	InStock         bool
+	LowStock        bool
+	IncludeArchived bool
+	Sort            string
+	Page            int
+	Limit           int
+}
+
+type ProductList struct {
+	Data       []Product  `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// ProductSortColumns - nilai sort yang diterima (prefix "-" untuk descending)
+var ProductSortColumns = map[string]string{
+	"id":    "id",
+	"name":  "name",
+	"price": "price",
+	"stock": "stock",
+}
+
+var (
+	ErrProductNotFound = errors.New("produk tidak ditemukan")
+	// ErrProductArchived - produk sudah diarsipkan dan tidak bisa dijual
+	ErrProductArchived = errors.New("produk sudah diarsipkan")
+)
@@ -0,0 +1,261 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// Jenis promo:
+//   - percent: potongan Value persen dari setiap line yang memenuhi syarat
+//   - fixed: potongan Value rupiah dari total line yang memenuhi syarat
+//   - buy_x_get_y: setiap BuyQuantity + FreeQuantity unit, FreeQuantity unit termurah gratis
+//   - bundle_price: setiap BundleQuantity unit dijual seharga BundlePrice
+const (
+	PromotionTypePercent     = "percent"
+	PromotionTypeFixed       = "fixed"
+	PromotionTypeBuyXGetY    = "buy_x_get_y"
+	PromotionTypeBundlePrice = "bundle_price"
+)
+
+var PromotionTypes = []string{PromotionTypePercent, PromotionTypeFixed, PromotionTypeBuyXGetY, PromotionTypeBundlePrice}
+
+var (
+	ErrPromotionNotFound = errors.New("promotion not found")
+	ErrPromotionArchived = errors.New("promotion is archived")
+)
+
+// Promotion - aturan promo yang otomatis dievaluasi saat checkout.
+// Syarat: ProductIDs/CategoryIDs (keduanya kosong berarti semua produk), MinQuantity total quantity
+// item yang memenuhi syarat, StartDate/EndDate (YYYY-MM-DD), DaysOfWeek (0 = Minggu) dan
+// StartTime/EndTime (HH:MM, boleh melewati tengah malam) untuk happy hour. Semua syarat waktu opsional.
+// Promo dengan Priority lebih tinggi dievaluasi lebih dulu, dan satu line hanya bisa kena satu promo.
+type Promotion struct {
+	ID             int        `json:"id"`
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Type           string     `json:"type"`
+	Value          float64    `json:"value"`
+	BuyQuantity    int        `json:"buy_quantity"`
+	FreeQuantity   int        `json:"free_quantity"`
+	BundleQuantity int        `json:"bundle_quantity"`
+	BundlePrice    int        `json:"bundle_price"`
+	ProductIDs     []int      `json:"product_ids"`
+	CategoryIDs    []int      `json:"category_ids"`
+	MinQuantity    float64    `json:"min_quantity"`
+	StartDate      *string    `json:"start_date"`
+	EndDate        *string    `json:"end_date"`
+	DaysOfWeek     []int      `json:"days_of_week"`
+	StartTime      *string    `json:"start_time"`
+	EndTime        *string    `json:"end_time"`
+	Priority       int        `json:"priority"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ArchivedAt     *time.Time `json:"archived_at,omitempty"`
+}
+
+// AppliedPromotion - promo yang berlaku pada transaksi beserta total potongannya
+type AppliedPromotion struct {
+	PromotionID int    `json:"promotion_id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Amount      int    `json:"amount"`
+}
+
+// PromotionLine - satu line cart yang dievaluasi. Amount adalah nilai kotor line.
+type PromotionLine struct {
+	ProductID  int
+	CategoryID *int
+	UnitPrice  int
+	Quantity   float64
+	Amount     int
+}
+
+func IsValidPromotionType(promotionType string) bool {
+	for _, t := range PromotionTypes {
+		if t == promotionType {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveAt - cek syarat tanggal, hari dan jam pada waktu at (zona waktu server)
+func (p *Promotion) ActiveAt(at time.Time) bool {
+	date := at.Format("2006-01-02")
+	if p.StartDate != nil && date < *p.StartDate {
+		return false
+	}
+	if p.EndDate != nil && date > *p.EndDate {
+		return false
+	}
+
+	if len(p.DaysOfWeek) > 0 {
+		matched := false
+		for _, d := range p.DaysOfWeek {
+			if time.Weekday(d) == at.Weekday() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if p.StartTime != nil && p.EndTime != nil {
+		clock := at.Format("15:04")
+		if *p.StartTime <= *p.EndTime {
+			return clock >= *p.StartTime && clock < *p.EndTime
+		}
+		// Melewati tengah malam, misal 22:00 - 02:00
+		return clock >= *p.StartTime || clock < *p.EndTime
+	}
+	return true
+}
+
+// Matches - cek syarat produk/kategori untuk satu line
+func (p *Promotion) Matches(line PromotionLine) bool {
+	if len(p.ProductIDs) == 0 && len(p.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range p.ProductIDs {
+		if id == line.ProductID {
+			return true
+		}
+	}
+	if line.CategoryID != nil {
+		for _, id := range p.CategoryIDs {
+			if id == *line.CategoryID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ApplyPromotions - evaluasi promotions (sudah urut prioritas) terhadap lines pada waktu at.
+// Mengembalikan promo yang berlaku dan potongan promo per line (urutan sama dengan lines).
+func ApplyPromotions(promotions []Promotion, lines []PromotionLine, at time.Time) ([]AppliedPromotion, []int) {
+	applied := make([]AppliedPromotion, 0)
+	amounts := make([]int, len(lines))
+	claimed := make([]bool, len(lines))
+
+	for i := range promotions {
+		p := &promotions[i]
+		if !p.ActiveAt(at) {
+			continue
+		}
+
+		eligible := make([]int, 0)
+		quantity := 0.0
+		for j, line := range lines {
+			if !claimed[j] && line.Amount > 0 && p.Matches(line) {
+				eligible = append(eligible, j)
+				quantity += line.Quantity
+			}
+		}
+		if len(eligible) == 0 || RoundQuantity(quantity) < p.MinQuantity {
+			continue
+		}
+
+		discounts := p.discounts(lines, eligible)
+		total := 0
+		for k, j := range eligible {
+			// Potongan tidak boleh melebihi nilai line
+			if discounts[k] > lines[j].Amount {
+				discounts[k] = lines[j].Amount
+			}
+			total += discounts[k]
+		}
+		if total <= 0 {
+			continue
+		}
+
+		for k, j := range eligible {
+			amounts[j] = discounts[k]
+			claimed[j] = true
+		}
+		applied = append(applied, AppliedPromotion{PromotionID: p.ID, Name: p.Name, Type: p.Type, Amount: total})
+	}
+
+	return applied, amounts
+}
+
+// discounts - potongan per line eligible (urutan sama dengan eligible)
+func (p *Promotion) discounts(lines []PromotionLine, eligible []int) []int {
+	discounts := make([]int, len(eligible))
+	switch p.Type {
+	case PromotionTypePercent:
+		for k, j := range eligible {
+			discounts[k] = int(math.Round(float64(lines[j].Amount) * p.Value / 100))
+		}
+
+	case PromotionTypeFixed:
+		amounts := make([]int, len(eligible))
+		total := 0
+		for k, j := range eligible {
+			amounts[k] = lines[j].Amount
+			total += amounts[k]
+		}
+		discounts = AllocateDiscount(int(math.Min(p.Value, float64(total))), amounts)
+
+	case PromotionTypeBuyXGetY:
+		// Unit gratis diambil dari line dengan harga satuan termurah
+		group := p.BuyQuantity + p.FreeQuantity
+		free := wholeUnits(lines, eligible) / group * p.FreeQuantity
+		for _, k := range sortedByUnitPrice(lines, eligible, false) {
+			if free == 0 {
+				break
+			}
+			take := int(math.Min(float64(free), math.Floor(lines[eligible[k]].Quantity)))
+			discounts[k] = take * lines[eligible[k]].UnitPrice
+			free -= take
+		}
+
+	case PromotionTypeBundlePrice:
+		// Bundle diisi unit termahal dulu, selisih nilai normal dan harga bundle dibagi ke line-nya
+		units := wholeUnits(lines, eligible) / p.BundleQuantity * p.BundleQuantity
+		values := make([]int, len(eligible))
+		normal := 0
+		for _, k := range sortedByUnitPrice(lines, eligible, true) {
+			if units == 0 {
+				break
+			}
+			take := int(math.Min(float64(units), math.Floor(lines[eligible[k]].Quantity)))
+			values[k] = take * lines[eligible[k]].UnitPrice
+			normal += values[k]
+			units -= take
+		}
+		bundles := wholeUnits(lines, eligible) / p.BundleQuantity
+		if saving := normal - bundles*p.BundlePrice; saving > 0 {
+			discounts = AllocateDiscount(saving, values)
+		}
+	}
+	return discounts
+}
+
+// wholeUnits - jumlah unit utuh dari line eligible, quantity desimal dibulatkan ke bawah
+func wholeUnits(lines []PromotionLine, eligible []int) int {
+	units := 0
+	for _, j := range eligible {
+		units += int(math.Floor(RoundQuantity(lines[j].Quantity)))
+	}
+	return units
+}
+
+// sortedByUnitPrice - index ke eligible, urut harga satuan naik (atau turun kalau desc)
+func sortedByUnitPrice(lines []PromotionLine, eligible []int, desc bool) []int {
+	order := make([]int, len(eligible))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		pa, pb := lines[eligible[order[a]]].UnitPrice, lines[eligible[order[b]]].UnitPrice
+		if desc {
+			return pa > pb
+		}
+		return pa < pb
+	})
+	return order
+}
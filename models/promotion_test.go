@@ -0,0 +1,128 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int { return &v }
+
+func strPtr(v string) *string { return &v }
+
+func TestPromotionActiveAt(t *testing.T) {
+	// Sabtu, 17 Oktober 2026
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 10, 17, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name      string
+		promotion Promotion
+		at        time.Time
+		want      bool
+	}{
+		{"no conditions", Promotion{}, at(12, 0), true},
+		{"inside date range", Promotion{StartDate: strPtr("2026-10-01"), EndDate: strPtr("2026-10-31")}, at(12, 0), true},
+		{"before start date", Promotion{StartDate: strPtr("2026-10-18")}, at(12, 0), false},
+		{"after end date", Promotion{EndDate: strPtr("2026-10-16")}, at(12, 0), false},
+		{"weekend", Promotion{DaysOfWeek: []int{0, 6}}, at(12, 0), true},
+		{"weekday only", Promotion{DaysOfWeek: []int{1, 2, 3, 4, 5}}, at(12, 0), false},
+		{"happy hour", Promotion{StartTime: strPtr("15:00"), EndTime: strPtr("17:00")}, at(16, 0), true},
+		{"happy hour end is exclusive", Promotion{StartTime: strPtr("15:00"), EndTime: strPtr("17:00")}, at(17, 0), false},
+		{"past midnight, before midnight", Promotion{StartTime: strPtr("22:00"), EndTime: strPtr("02:00")}, at(23, 30), true},
+		{"past midnight, after midnight", Promotion{StartTime: strPtr("22:00"), EndTime: strPtr("02:00")}, at(1, 59), true},
+		{"past midnight, at end", Promotion{StartTime: strPtr("22:00"), EndTime: strPtr("02:00")}, at(2, 0), false},
+		{"past midnight, midday", Promotion{StartTime: strPtr("22:00"), EndTime: strPtr("02:00")}, at(12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.promotion.ActiveAt(tt.at); got != tt.want {
+				t.Errorf("ActiveAt(%s) = %v, want %v", tt.at.Format("2006-01-02 15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPromotions(t *testing.T) {
+	lines := []PromotionLine{
+		{ProductID: 1, CategoryID: intPtr(10), UnitPrice: 5000, Quantity: 3, Amount: 15000},
+		{ProductID: 2, CategoryID: intPtr(10), UnitPrice: 3000, Quantity: 2, Amount: 6000},
+		{ProductID: 3, CategoryID: intPtr(20), UnitPrice: 10000, Quantity: 1, Amount: 10000},
+	}
+	at := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name        string
+		promotions  []Promotion
+		at          time.Time
+		wantApplied []int
+		wantAmounts []int
+	}{
+		{
+			"percent on a category",
+			[]Promotion{{ID: 1, Type: PromotionTypePercent, Value: 10, CategoryIDs: []int{10}}},
+			at, []int{2100}, []int{1500, 600, 0},
+		},
+		{
+			"fixed amount spread over matching lines",
+			[]Promotion{{ID: 1, Type: PromotionTypeFixed, Value: 3000, CategoryIDs: []int{10}}},
+			at, []int{3000}, []int{2143, 857, 0},
+		},
+		{
+			"fixed amount capped at the matching total",
+			[]Promotion{{ID: 1, Type: PromotionTypeFixed, Value: 50000, ProductIDs: []int{3}}},
+			at, []int{10000}, []int{0, 0, 10000},
+		},
+		{
+			"buy 2 get 1 takes the cheapest unit",
+			[]Promotion{{ID: 1, Type: PromotionTypeBuyXGetY, BuyQuantity: 2, FreeQuantity: 1, CategoryIDs: []int{10}}},
+			at, []int{3000}, []int{0, 3000, 0},
+		},
+		{
+			"bundle price fills with the most expensive units",
+			[]Promotion{{ID: 1, Type: PromotionTypeBundlePrice, BundleQuantity: 2, BundlePrice: 12000, ProductIDs: []int{1, 3}}},
+			at, []int{1000}, []int{600, 0, 400},
+		},
+		{
+			"higher priority claims the line first",
+			[]Promotion{
+				{ID: 1, Type: PromotionTypePercent, Value: 50, ProductIDs: []int{2}, Priority: 10},
+				{ID: 2, Type: PromotionTypePercent, Value: 10},
+			},
+			at, []int{3000, 2500}, []int{1500, 3000, 1000},
+		},
+		{
+			"min quantity not reached",
+			[]Promotion{{ID: 1, Type: PromotionTypePercent, Value: 10, CategoryIDs: []int{20}, MinQuantity: 2}},
+			at, []int{}, []int{0, 0, 0},
+		},
+		{
+			"happy hour past midnight",
+			[]Promotion{{ID: 1, Type: PromotionTypeFixed, Value: 1000, ProductIDs: []int{3}, StartTime: strPtr("22:00"), EndTime: strPtr("02:00")}},
+			time.Date(2026, 10, 18, 0, 30, 0, 0, time.Local), []int{1000}, []int{0, 0, 1000},
+		},
+		{
+			"happy hour outside its window",
+			[]Promotion{{ID: 1, Type: PromotionTypeFixed, Value: 1000, ProductIDs: []int{3}, StartTime: strPtr("22:00"), EndTime: strPtr("02:00")}},
+			at, []int{}, []int{0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied, amounts := ApplyPromotions(tt.promotions, lines, tt.at)
+			gotApplied := make([]int, len(applied))
+			for i, a := range applied {
+				gotApplied[i] = a.Amount
+			}
+			if !reflect.DeepEqual(gotApplied, tt.wantApplied) {
+				t.Errorf("applied amounts = %v, want %v", gotApplied, tt.wantApplied)
+			}
+			if !reflect.DeepEqual(amounts, tt.wantAmounts) {
+				t.Errorf("line amounts = %v, want %v", amounts, tt.wantAmounts)
+			}
+		})
+	}
+}
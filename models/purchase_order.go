@@ -0,0 +1,140 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Status purchase order. Draft masih bisa diubah, ordered dan partially_received bisa diterima
+// (goods receipt), received dan cancelled sudah final.
+const (
+	PurchaseOrderStatusDraft             = "draft"
+	PurchaseOrderStatusOrdered           = "ordered"
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	PurchaseOrderStatusReceived          = "received"
+	PurchaseOrderStatusCancelled         = "cancelled"
+)
+
+var PurchaseOrderStatuses = []string{
+	PurchaseOrderStatusDraft, PurchaseOrderStatusOrdered, PurchaseOrderStatusPartiallyReceived,
+	PurchaseOrderStatusReceived, PurchaseOrderStatusCancelled,
+}
+
+var (
+	ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+	// ErrInvalidPurchaseOrderStatus - aksi tidak boleh dilakukan pada status PO saat ini
+	ErrInvalidPurchaseOrderStatus = errors.New("action not allowed for the purchase order status")
+	// ErrOverReceipt - quantity yang diterima melebihi sisa yang dipesan
+	ErrOverReceipt = errors.New("received quantity exceeds the remaining ordered quantity")
+)
+
+// PurchaseOrder - TotalCost adalah jumlah subtotal semua item (quantity x unit_cost).
+// Barang yang diterima masuk ke stok OutletID.
+type PurchaseOrder struct {
+	ID         int                 `json:"id"`
+	SupplierID int                 `json:"supplier_id"`
+	OutletID   int                 `json:"outlet_id"`
+	Supplier   *Supplier           `json:"supplier,omitempty"`
+	Status     string              `json:"status"`
+	Notes      string              `json:"notes,omitempty"`
+	TotalCost  int                 `json:"total_cost"`
+	CreatedAt  time.Time           `json:"created_at"`
+	OrderedAt  *time.Time          `json:"ordered_at,omitempty"`
+	ReceivedAt *time.Time          `json:"received_at,omitempty"`
+	Items      []PurchaseOrderItem `json:"items,omitempty"`
+	Receipts   []GoodsReceipt      `json:"receipts,omitempty"`
+}
+
+type PurchaseOrderItem struct {
+	ID               int     `json:"id"`
+	PurchaseOrderID  int     `json:"purchase_order_id"`
+	ProductID        int     `json:"product_id"`
+	ProductName      string  `json:"product_name"`
+	Quantity         float64 `json:"quantity"`
+	ReceivedQuantity float64 `json:"received_quantity"`
+	UnitCost         int     `json:"unit_cost"`
+	Subtotal         int     `json:"subtotal"`
+}
+
+// PurchaseOrderRequest - body create/update PO (hanya selama draft). OutletID 0 berarti outlet default.
+type PurchaseOrderRequest struct {
+	SupplierID int                        `json:"supplier_id"`
+	OutletID   int                        `json:"outlet_id"`
+	Notes      string                     `json:"notes"`
+	Items      []PurchaseOrderItemRequest `json:"items"`
+}
+
+type PurchaseOrderItemRequest struct {
+	ProductID int     `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+	UnitCost  int     `json:"unit_cost"`
+}
+
+// GoodsReceipt - satu kali penerimaan barang untuk sebuah PO
+type GoodsReceipt struct {
+	ID              int                `json:"id"`
+	PurchaseOrderID int                `json:"purchase_order_id"`
+	Notes           string             `json:"notes,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+	Items           []GoodsReceiptItem `json:"items"`
+}
+
+type GoodsReceiptItem struct {
+	ID                  int     `json:"id"`
+	GoodsReceiptID      int     `json:"goods_receipt_id"`
+	PurchaseOrderItemID int     `json:"purchase_order_item_id"`
+	ProductID           int     `json:"product_id"`
+	Quantity            float64 `json:"quantity"`
+	UnitCost            int     `json:"unit_cost"`
+	BatchNumber         string  `json:"batch_number,omitempty"`
+	ExpiryDate          *string `json:"expiry_date,omitempty"`
+}
+
+// GoodsReceiptRequest - Items kosong berarti terima semua sisa item PO.
+// UnitCost opsional, default unit_cost di PO (misalnya kalau harga di faktur berbeda).
+// BatchNumber wajib untuk produk batch-tracked, jadi penerimaan tanpa items tidak bisa dipakai untuk produk itu.
+type GoodsReceiptRequest struct {
+	Notes string                    `json:"notes"`
+	Items []GoodsReceiptItemRequest `json:"items"`
+}
+
+type GoodsReceiptItemRequest struct {
+	PurchaseOrderItemID int     `json:"purchase_order_item_id"`
+	Quantity            float64 `json:"quantity"`
+	UnitCost            *int    `json:"unit_cost"`
+	BatchNumber         string  `json:"batch_number"`
+	ExpiryDate          *string `json:"expiry_date"`
+}
+
+type PurchaseOrderFilter struct {
+	Status     string
+	SupplierID int
+	OutletID   int
+	Page       int
+	Limit      int
+}
+
+type PurchaseOrderList struct {
+	Data       []PurchaseOrder `json:"data"`
+	Pagination Pagination      `json:"pagination"`
+}
+
+func IsValidPurchaseOrderStatus(status string) bool {
+	for _, s := range PurchaseOrderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// MovingAverageCost - harga pokok baru setelah menerima quantity dengan unitCost.
+// Stok negatif dianggap 0 supaya harga pokok tidak terbalik.
+func MovingAverageCost(stock float64, costPrice int, quantity float64, unitCost int) int {
+	stock = math.Max(stock, 0)
+	if stock+quantity <= 0 {
+		return unitCost
+	}
+	return int(math.Round((stock*float64(costPrice) + quantity*float64(unitCost)) / (stock + quantity)))
+}
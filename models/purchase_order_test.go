@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestMovingAverageCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		stock     float64
+		costPrice int
+		quantity  float64
+		unitCost  int
+		want      int
+	}{
+		{"equal quantities", 10, 1000, 10, 2000, 1500},
+		{"no stock on hand", 0, 1000, 5, 1200, 1200},
+		{"negative stock counts as zero", -3, 1000, 5, 2000, 2000},
+		{"rounds down", 3, 1000, 1, 1001, 1000},
+		{"rounds up", 1, 1000, 2, 1001, 1001},
+		{"decimal quantities", 1.5, 20000, 0.5, 24000, 21000},
+		{"nothing received and no stock", 0, 1000, 0, 900, 900},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MovingAverageCost(tt.stock, tt.costPrice, tt.quantity, tt.unitCost); got != tt.want {
+				t.Errorf("MovingAverageCost(%g, %d, %g, %d) = %d, want %d", tt.stock, tt.costPrice, tt.quantity, tt.unitCost, got, tt.want)
+			}
+		})
+	}
+}
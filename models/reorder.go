@@ -0,0 +1,85 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+// ReorderReport - saran order ulang dari kecepatan penjualan Days hari terakhir,
+// cukup untuk CoverDays hari ke depan di atas min_stock. OutletID nil berarti total semua outlet.
+type ReorderReport struct {
+	OutletID           *int                `json:"outlet_id"`
+	Days               int                 `json:"days"`
+	CoverDays          int                 `json:"cover_days"`
+	TotalEstimatedCost int                 `json:"total_estimated_cost"`
+	Items              []ReorderSuggestion `json:"items"`
+}
+
+// ReorderSuggestion - DaysOfStock nil kalau produk tidak terjual selama periode
+type ReorderSuggestion struct {
+	ProductID     int      `json:"product_id"`
+	Name          string   `json:"name"`
+	SKU           string   `json:"sku,omitempty"`
+	Unit          string   `json:"unit"`
+	Stock         float64  `json:"stock"`
+	MinStock      float64  `json:"min_stock"`
+	ReorderQty    float64  `json:"reorder_qty"`
+	CostPrice     int      `json:"cost_price"`
+	SoldQty       float64  `json:"sold_qty"`
+	AvgDailySales float64  `json:"avg_daily_sales"`
+	DaysOfStock   *float64 `json:"days_of_stock"`
+	SuggestedQty  float64  `json:"suggested_qty"`
+	EstimatedCost int      `json:"estimated_cost"`
+}
+
+// Suggest - hitung rata-rata penjualan harian, sisa hari stok dan jumlah yang disarankan.
+// Kebutuhan = rata-rata harian x coverDays + min_stock - stok. Kalau stok sudah di bawah
+// min_stock atau ada kebutuhan, order minimal sebesar reorder_qty. Produk pcs dibulatkan ke atas.
+func (s *ReorderSuggestion) Suggest(days, coverDays int) {
+	s.AvgDailySales = RoundQuantity(s.SoldQty / float64(days))
+	if s.AvgDailySales > 0 {
+		daysOfStock := math.Round(s.Stock/s.AvgDailySales*10) / 10
+		s.DaysOfStock = &daysOfStock
+	}
+
+	need := s.SoldQty/float64(days)*float64(coverDays) + s.MinStock - s.Stock
+	belowMin := s.MinStock > 0 && s.Stock <= s.MinStock
+	if need <= 0 && !belowMin {
+		s.SuggestedQty = 0
+		s.EstimatedCost = 0
+		return
+	}
+
+	suggested := math.Max(need, s.ReorderQty)
+	if s.Unit == UnitPcs {
+		suggested = math.Ceil(suggested)
+	} else {
+		suggested = math.Ceil(suggested*1000) / 1000
+	}
+	s.SuggestedQty = suggested
+	s.EstimatedCost = int(math.Round(suggested * float64(s.CostPrice)))
+}
+
+// NewReorderReport - hitung saran untuk semua kandidat, ambil yang perlu diorder dan
+// urutkan dari yang paling cepat habis. Produk tanpa penjualan ditaruh di akhir.
+func NewReorderReport(candidates []ReorderSuggestion, days, coverDays int) *ReorderReport {
+	report := &ReorderReport{Days: days, CoverDays: coverDays, Items: make([]ReorderSuggestion, 0)}
+	for _, c := range candidates {
+		c.Suggest(days, coverDays)
+		if c.SuggestedQty <= 0 {
+			continue
+		}
+		report.Items = append(report.Items, c)
+		report.TotalEstimatedCost += c.EstimatedCost
+	}
+
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		a, b := report.Items[i].DaysOfStock, report.Items[j].DaysOfStock
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a < *b
+	})
+
+	return report
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Jenis pergerakan stok
+const (
+	StockMovementSale        = "sale"
+	StockMovementRefund      = "refund"
+	StockMovementReceipt     = "receipt"
+	StockMovementAdjustment  = "adjustment"
+	StockMovementDamage      = "damage"
+	StockMovementOpname      = "opname"
+	StockMovementTransferOut = "transfer_out"
+	StockMovementTransferIn  = "transfer_in"
+)
+
+var StockMovementTypes = []string{
+	StockMovementSale, StockMovementRefund, StockMovementReceipt,
+	StockMovementAdjustment, StockMovementDamage, StockMovementOpname,
+	StockMovementTransferOut, StockMovementTransferIn,
+}
+
+// ManualStockMovementTypes - jenis yang boleh dicatat lewat endpoint stock-adjustments,
+// sale dan refund hanya dicatat oleh checkout/refund
+var ManualStockMovementTypes = []string{StockMovementReceipt, StockMovementAdjustment, StockMovementDamage}
+
+var (
+	ErrStockNotEditable = errors.New("stock cannot be changed by updating the product, use stock adjustments")
+	ErrNegativeStock    = errors.New("stock cannot go below zero")
+)
+
+// StockMovement - satu baris ledger stok. Quantity adalah selisihnya, negatif kalau stok berkurang.
+// StockBefore/StockAfter adalah stok produk di outlet movement tersebut.
+// ReferenceID menunjuk ke transaksi (sale), refund (refund) atau stock transfer (transfer_out/transfer_in),
+// opsional untuk jenis lain.
+// Batches berisi batch yang terkena movement untuk produk batch-tracked, quantity-nya bertanda sama
+// dengan movement. Pemanggil boleh mengisinya lebih dulu untuk memilih batch sendiri (checkout, refund);
+// kalau kosong ledger memakai BatchNumber/ExpiryDate untuk stok masuk atau FEFO untuk stok keluar.
+type StockMovement struct {
+	ID          int               `json:"id"`
+	ProductID   int               `json:"product_id"`
+	OutletID    int               `json:"outlet_id"`
+	Type        string            `json:"type"`
+	Quantity    float64           `json:"quantity"`
+	StockBefore float64           `json:"stock_before"`
+	StockAfter  float64           `json:"stock_after"`
+	Reason      string            `json:"reason,omitempty"`
+	ReferenceID *int              `json:"reference_id,omitempty"`
+	Batches     []BatchAllocation `json:"batches,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	BatchNumber string            `json:"-"`
+	ExpiryDate  *string           `json:"-"`
+}
+
+// StockAdjustmentRequest - body POST /api/produk/{id}/stock-adjustments.
+// Quantity bertanda: positif menambah stok, negatif mengurangi.
+// BatchNumber wajib untuk stok masuk produk batch-tracked; untuk pengurangan opsional, kosong berarti FEFO.
+// OutletID 0 berarti outlet default.
+type StockAdjustmentRequest struct {
+	OutletID    int     `json:"outlet_id"`
+	Type        string  `json:"type"`
+	Quantity    float64 `json:"quantity"`
+	Reason      string  `json:"reason"`
+	ReferenceID *int    `json:"reference_id"`
+	BatchNumber string  `json:"batch_number"`
+	ExpiryDate  *string `json:"expiry_date"`
+}
+
+type StockMovementFilter struct {
+	ProductID int
+	OutletID  int
+	Type      string
+	Page      int
+	Limit     int
+}
+
+type StockMovementList struct {
+	Data       []StockMovement `json:"data"`
+	Pagination Pagination      `json:"pagination"`
+}
+
+func IsManualStockMovementType(movementType string) bool {
+	for _, t := range ManualStockMovementTypes {
+		if t == movementType {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidStockMovementType(movementType string) bool {
+	for _, t := range StockMovementTypes {
+		if t == movementType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Status sesi stock opname
+const (
+	StockOpnameStatusOpen      = "open"
+	StockOpnameStatusPosted    = "posted"
+	StockOpnameStatusCancelled = "cancelled"
+)
+
+var StockOpnameStatuses = []string{StockOpnameStatusOpen, StockOpnameStatusPosted, StockOpnameStatusCancelled}
+
+var (
+	ErrStockOpnameNotFound = errors.New("stock opname not found")
+	ErrStockOpnameNotOpen  = errors.New("stock opname is not open")
+	ErrProductNotInOpname  = errors.New("product is not part of this stock opname")
+)
+
+// StockOpname - satu sesi hitung fisik. Stok sistem dan harga pokok di-snapshot saat sesi dibuka,
+// Items dan Summary hanya diisi di detail. Satu sesi menghitung stok satu outlet.
+type StockOpname struct {
+	ID         int                 `json:"id"`
+	OutletID   int                 `json:"outlet_id"`
+	Status     string              `json:"status"`
+	Notes      string              `json:"notes,omitempty"`
+	CategoryID *int                `json:"category_id,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	PostedAt   *time.Time          `json:"posted_at,omitempty"`
+	Summary    *StockOpnameSummary `json:"summary,omitempty"`
+	Items      []StockOpnameItem   `json:"items,omitempty"`
+}
+
+// StockOpnameItem - CountedQuantity, Variance dan VarianceValue nil selama produk belum dihitung.
+// Variance = counted - system_stock, VarianceValue = variance x unit_cost.
+type StockOpnameItem struct {
+	ProductID       int      `json:"product_id"`
+	ProductName     string   `json:"product_name"`
+	SKU             string   `json:"sku,omitempty"`
+	Unit            string   `json:"unit"`
+	SystemStock     float64  `json:"system_stock"`
+	CountedQuantity *float64 `json:"counted_quantity"`
+	Variance        *float64 `json:"variance"`
+	UnitCost        int      `json:"unit_cost"`
+	VarianceValue   *int     `json:"variance_value"`
+}
+
+// StockOpnameSummary - nilai selisih dihitung dari harga pokok, surplus positif dan shortage negatif
+type StockOpnameSummary struct {
+	TotalItems       int `json:"total_items"`
+	CountedItems     int `json:"counted_items"`
+	UncountedItems   int `json:"uncounted_items"`
+	VarianceItems    int `json:"variance_items"`
+	SurplusValue     int `json:"surplus_value"`
+	ShortageValue    int `json:"shortage_value"`
+	NetVarianceValue int `json:"net_variance_value"`
+}
+
+// CreateStockOpnameRequest - CategoryID membatasi sesi ke satu kategori, kosong berarti semua produk aktif.
+// OutletID 0 berarti outlet default.
+type CreateStockOpnameRequest struct {
+	OutletID   int    `json:"outlet_id"`
+	Notes      string `json:"notes"`
+	CategoryID *int   `json:"category_id"`
+}
+
+// StockOpnameCountRequest - satu batch hasil hitung. Hitungan untuk produk yang sama dijumlahkan
+// (misalnya rak berbeda oleh penghitung berbeda), koreksi bisa dikirim dengan quantity negatif.
+type StockOpnameCountRequest struct {
+	CountedBy string                 `json:"counted_by"`
+	Items     []StockOpnameCountItem `json:"items"`
+}
+
+type StockOpnameCountItem struct {
+	ProductID int     `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+}
+
+type StockOpnameFilter struct {
+	Status   string
+	OutletID int
+	Page     int
+	Limit    int
+}
+
+type StockOpnameList struct {
+	Data       []StockOpname `json:"data"`
+	Pagination Pagination    `json:"pagination"`
+}
+
+func IsValidStockOpnameStatus(status string) bool {
+	for _, s := range StockOpnameStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Summarize - isi variance per item dan ringkasan nilainya
+func (o *StockOpname) Summarize() {
+	summary := &StockOpnameSummary{TotalItems: len(o.Items)}
+	for i := range o.Items {
+		item := &o.Items[i]
+		if item.CountedQuantity == nil {
+			summary.UncountedItems++
+			continue
+		}
+		summary.CountedItems++
+
+		variance := RoundQuantity(*item.CountedQuantity - item.SystemStock)
+		value := int(math.Round(variance * float64(item.UnitCost)))
+		item.Variance = &variance
+		item.VarianceValue = &value
+
+		if variance != 0 {
+			summary.VarianceItems++
+		}
+		if value > 0 {
+			summary.SurplusValue += value
+		} else {
+			summary.ShortageValue += value
+		}
+	}
+	summary.NetVarianceValue = summary.SurplusValue + summary.ShortageValue
+	o.Summary = summary
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Status transfer stok. Draft masih bisa dibatalkan, in_transit sudah keluar dari outlet asal
+// dan menunggu diterima, received dan cancelled sudah final.
+const (
+	StockTransferStatusDraft     = "draft"
+	StockTransferStatusInTransit = "in_transit"
+	StockTransferStatusReceived  = "received"
+	StockTransferStatusCancelled = "cancelled"
+)
+
+var StockTransferStatuses = []string{
+	StockTransferStatusDraft, StockTransferStatusInTransit, StockTransferStatusReceived, StockTransferStatusCancelled,
+}
+
+var (
+	ErrStockTransferNotFound = errors.New("stock transfer not found")
+	// ErrInvalidStockTransferStatus - aksi tidak boleh dilakukan pada status transfer saat ini
+	ErrInvalidStockTransferStatus = errors.New("action not allowed for the stock transfer status")
+	// ErrSameOutletTransfer - outlet asal dan tujuan harus berbeda
+	ErrSameOutletTransfer = errors.New("source and destination outlet must be different")
+)
+
+// StockTransfer - pemindahan stok dari SourceOutletID ke DestinationOutletID.
+// Stok outlet asal berkurang saat dispatch, stok outlet tujuan bertambah saat receive;
+// di antaranya quantity berstatus in transit dan tidak ada di stok outlet mana pun.
+type StockTransfer struct {
+	ID                    int                 `json:"id"`
+	SourceOutletID        int                 `json:"source_outlet_id"`
+	SourceOutletName      string              `json:"source_outlet_name"`
+	DestinationOutletID   int                 `json:"destination_outlet_id"`
+	DestinationOutletName string              `json:"destination_outlet_name"`
+	Status                string              `json:"status"`
+	Notes                 string              `json:"notes,omitempty"`
+	ReceiveNotes          string              `json:"receive_notes,omitempty"`
+	CreatedAt             time.Time           `json:"created_at"`
+	DispatchedAt          *time.Time          `json:"dispatched_at,omitempty"`
+	ReceivedAt            *time.Time          `json:"received_at,omitempty"`
+	Items                 []StockTransferItem `json:"items,omitempty"`
+}
+
+// StockTransferItem - ReceivedQuantity dan Discrepancy nil sampai transfer diterima.
+// Discrepancy = received - quantity, negatif berarti barang kurang (hilang/rusak di jalan).
+// Batches berisi batch outlet asal yang dikirim untuk produk batch-tracked.
+type StockTransferItem struct {
+	ID               int               `json:"id"`
+	StockTransferID  int               `json:"stock_transfer_id"`
+	ProductID        int               `json:"product_id"`
+	ProductName      string            `json:"product_name"`
+	Quantity         float64           `json:"quantity"`
+	ReceivedQuantity *float64          `json:"received_quantity"`
+	Discrepancy      *float64          `json:"discrepancy"`
+	DiscrepancyNote  string            `json:"discrepancy_note,omitempty"`
+	Batches          []BatchAllocation `json:"batches,omitempty"`
+}
+
+// StockTransferRequest - body create transfer (status draft)
+type StockTransferRequest struct {
+	SourceOutletID      int                        `json:"source_outlet_id"`
+	DestinationOutletID int                        `json:"destination_outlet_id"`
+	Notes               string                     `json:"notes"`
+	Items               []StockTransferItemRequest `json:"items"`
+}
+
+type StockTransferItemRequest struct {
+	ProductID int     `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+}
+
+// StockTransferReceiveRequest - item yang tidak disebut dianggap diterima lengkap
+type StockTransferReceiveRequest struct {
+	Notes string                     `json:"notes"`
+	Items []StockTransferReceiveItem `json:"items"`
+}
+
+// StockTransferReceiveItem - ReceivedQuantity 0 sampai quantity yang dikirim, Note menjelaskan selisihnya
+type StockTransferReceiveItem struct {
+	StockTransferItemID int     `json:"stock_transfer_item_id"`
+	ReceivedQuantity    float64 `json:"received_quantity"`
+	Note                string  `json:"note"`
+}
+
+// StockTransferFilter - OutletID mencocokkan outlet asal maupun tujuan
+type StockTransferFilter struct {
+	Status   string
+	OutletID int
+	Page     int
+	Limit    int
+}
+
+type StockTransferList struct {
+	Data       []StockTransfer `json:"data"`
+	Pagination Pagination      `json:"pagination"`
+}
+
+func IsValidStockTransferStatus(status string) bool {
+	for _, s := range StockTransferStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
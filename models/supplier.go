@@ -0,0 +1,22 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Supplier - pemasok barang, ArchivedAt terisi kalau sudah diarsipkan (soft delete)
+type Supplier struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	ContactName string     `json:"contact_name"`
+	Phone       string     `json:"phone"`
+	Email       string     `json:"email"`
+	Address     string     `json:"address"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+}
+
+var (
+	ErrSupplierNotFound = errors.New("supplier tidak ditemukan")
+	ErrSupplierArchived = errors.New("supplier sudah diarsipkan")
+)
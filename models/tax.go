@@ -0,0 +1,158 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultTaxCategory - kategori pajak produk yang tidak menyebut tax_category
+const DefaultTaxCategory = "standard"
+
+// Aturan pembulatan rupiah untuk pajak, service charge dan pembayaran tunai
+const (
+	RoundingNearest = "nearest"
+	RoundingUp      = "up"
+	RoundingDown    = "down"
+)
+
+var RoundingModes = []string{RoundingNearest, RoundingUp, RoundingDown}
+
+// ErrUnknownTaxCategory - tax_category produk tidak ada di konfigurasi TAX_RATES
+var ErrUnknownTaxCategory = errors.New("unknown tax category")
+
+func IsValidRoundingMode(mode string) bool {
+	for _, m := range RoundingModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundAmount - bulatkan nilai rupiah pecahan sesuai mode. Selisih kecil akibat floating point
+// (misal 1100.0000000002) dibuang dulu supaya mode up/down tidak meleset satu rupiah.
+func RoundAmount(value float64, mode string) int {
+	value = math.Round(value*1e6) / 1e6
+	switch mode {
+	case RoundingUp:
+		return int(math.Ceil(value))
+	case RoundingDown:
+		return int(math.Floor(value))
+	default:
+		return int(math.Round(value))
+	}
+}
+
+// TaxRates - tarif pajak (persen) per kategori pajak produk
+type TaxRates map[string]float64
+
+// ParseTaxRates - parse konfigurasi "category:rate_percent", dipisah koma.
+// Contoh: "standard:11,exempt:0"
+func ParseTaxRates(config string) (TaxRates, error) {
+	rates := make(TaxRates)
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("tax rate %q must be category:rate_percent", entry)
+		}
+		rate, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || rate < 0 || rate > 100 {
+			return nil, fmt.Errorf("tax rate %q must be between 0 and 100", parts[1])
+		}
+		rates[strings.TrimSpace(parts[0])] = rate
+	}
+
+	return rates, nil
+}
+
+// Categories - nama kategori pajak yang dikonfigurasi, urut abjad
+func (r TaxRates) Categories() []string {
+	categories := make([]string, 0, len(r))
+	for c := range r {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// TaxConfig - aturan pajak checkout. Inclusive berarti harga jual produk sudah termasuk pajak.
+// ServiceChargePercent dihitung dari DPP barang dan ikut dikenai pajak sesuai tarif line-nya.
+// Rounding berlaku untuk setiap nilai pajak dan service charge yang dihitung.
+type TaxConfig struct {
+	Rates                TaxRates
+	Inclusive            bool
+	ServiceChargePercent float64
+	Rounding             string
+}
+
+// ApplyTax - hitung DPP, service charge, pajak dan total setiap line dari Subtotal (nilai bersih setelah
+// semua diskon). TaxCategory dan TaxRate line sudah diisi. Service charge dibagi ke line sebanding DPP-nya.
+func (c *TaxConfig) ApplyTax(items []TransactionDetail) {
+	bases := make([]int, len(items))
+	taxes := make([]int, len(items))
+	baseTotal := 0
+	for i := range items {
+		net := items[i].Subtotal
+		if c.Inclusive {
+			bases[i] = RoundAmount(float64(net)*100/(100+items[i].TaxRate), c.Rounding)
+			taxes[i] = net - bases[i]
+		} else {
+			bases[i] = net
+			taxes[i] = RoundAmount(float64(net)*items[i].TaxRate/100, c.Rounding)
+		}
+		baseTotal += bases[i]
+	}
+
+	serviceCharge := RoundAmount(float64(baseTotal)*c.ServiceChargePercent/100, c.Rounding)
+	for i, share := range AllocateDiscount(serviceCharge, bases) {
+		items[i].ServiceChargeAmount = share
+		items[i].TaxBaseAmount = bases[i] + share
+		items[i].TaxAmount = taxes[i] + RoundAmount(float64(share)*items[i].TaxRate/100, c.Rounding)
+		items[i].TotalAmount = items[i].TaxBaseAmount + items[i].TaxAmount
+	}
+}
+
+// TaxSummary - DPP dan pajak per kategori pajak, untuk struk dan laporan
+type TaxSummary struct {
+	TaxCategory   string  `json:"tax_category"`
+	TaxRate       float64 `json:"tax_rate"`
+	TaxBaseAmount int     `json:"tax_base_amount"`
+	TaxAmount     int     `json:"tax_amount"`
+}
+
+// SummarizeTaxes - jumlahkan DPP dan pajak line per kategori dan tarif, urut kategori
+func SummarizeTaxes(items []TransactionDetail) []TaxSummary {
+	summaries := make([]TaxSummary, 0)
+	for _, item := range items {
+		found := false
+		for i := range summaries {
+			if summaries[i].TaxCategory == item.TaxCategory && summaries[i].TaxRate == item.TaxRate {
+				summaries[i].TaxBaseAmount += item.TaxBaseAmount
+				summaries[i].TaxAmount += item.TaxAmount
+				found = true
+				break
+			}
+		}
+		if !found {
+			summaries = append(summaries, TaxSummary{
+				TaxCategory:   item.TaxCategory,
+				TaxRate:       item.TaxRate,
+				TaxBaseAmount: item.TaxBaseAmount,
+				TaxAmount:     item.TaxAmount,
+			})
+		}
+	}
+	sort.SliceStable(summaries, func(a, b int) bool {
+		return summaries[a].TaxCategory < summaries[b].TaxCategory
+	})
+	return summaries
+}
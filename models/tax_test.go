@@ -0,0 +1,125 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoundAmount(t *testing.T) {
+	tests := []struct {
+		value float64
+		mode  string
+		want  int
+	}{
+		{135.74, RoundingNearest, 136},
+		{135.3, RoundingNearest, 135},
+		{135.5, RoundingNearest, 136},
+		{135.3, RoundingUp, 136},
+		{135.74, RoundingDown, 135},
+		{1100.0000000002, RoundingUp, 1100},
+		{1099.9999999998, RoundingDown, 1100},
+	}
+
+	for _, tt := range tests {
+		if got := RoundAmount(tt.value, tt.mode); got != tt.want {
+			t.Errorf("RoundAmount(%v, %s) = %d, want %d", tt.value, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestApplyTax(t *testing.T) {
+	type line struct {
+		subtotal int
+		rate     float64
+	}
+	// want per line: DPP, service charge, pajak, total
+	tests := []struct {
+		name   string
+		config TaxConfig
+		lines  []line
+		want   [][4]int
+	}{
+		{
+			"inclusive",
+			TaxConfig{Inclusive: true, Rounding: RoundingNearest},
+			[]line{{11100, 11}, {5000, 0}},
+			[][4]int{{10000, 0, 1100, 11100}, {5000, 0, 0, 5000}},
+		},
+		{
+			"exclusive",
+			TaxConfig{Inclusive: false, Rounding: RoundingNearest},
+			[]line{{10000, 11}},
+			[][4]int{{10000, 0, 1100, 11100}},
+		},
+		{
+			"inclusive with taxed service charge",
+			TaxConfig{Inclusive: true, ServiceChargePercent: 5, Rounding: RoundingNearest},
+			[]line{{11100, 11}},
+			[][4]int{{10500, 500, 1155, 11655}},
+		},
+		{
+			"exclusive service charge spread over lines",
+			TaxConfig{Inclusive: false, ServiceChargePercent: 10, Rounding: RoundingNearest},
+			[]line{{10000, 11}, {5000, 0}},
+			[][4]int{{11000, 1000, 1210, 12210}, {5500, 500, 0, 5500}},
+		},
+		{
+			"exclusive rounding nearest",
+			TaxConfig{Rounding: RoundingNearest},
+			[]line{{1234, 11}},
+			[][4]int{{1234, 0, 136, 1370}},
+		},
+		{
+			"exclusive rounding down",
+			TaxConfig{Rounding: RoundingDown},
+			[]line{{1234, 11}},
+			[][4]int{{1234, 0, 135, 1369}},
+		},
+		{
+			"exclusive rounding up",
+			TaxConfig{Rounding: RoundingUp},
+			[]line{{1230, 11}},
+			[][4]int{{1230, 0, 136, 1366}},
+		},
+		{
+			"inclusive rounding keeps the line total",
+			TaxConfig{Inclusive: true, Rounding: RoundingDown},
+			[]line{{1000, 11}},
+			[][4]int{{900, 0, 100, 1000}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := make([]TransactionDetail, len(tt.lines))
+			for i, l := range tt.lines {
+				items[i] = TransactionDetail{Subtotal: l.subtotal, TaxRate: l.rate}
+			}
+			tt.config.ApplyTax(items)
+
+			got := make([][4]int, len(items))
+			for i, item := range items {
+				got[i] = [4]int{item.TaxBaseAmount, item.ServiceChargeAmount, item.TaxAmount, item.TotalAmount}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyTax = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeTaxes(t *testing.T) {
+	items := []TransactionDetail{
+		{TaxCategory: "standard", TaxRate: 11, TaxBaseAmount: 10000, TaxAmount: 1100},
+		{TaxCategory: "exempt", TaxRate: 0, TaxBaseAmount: 5000},
+		{TaxCategory: "standard", TaxRate: 11, TaxBaseAmount: 2000, TaxAmount: 220},
+	}
+	want := []TaxSummary{
+		{TaxCategory: "exempt", TaxRate: 0, TaxBaseAmount: 5000},
+		{TaxCategory: "standard", TaxRate: 11, TaxBaseAmount: 12000, TaxAmount: 1320},
+	}
+
+	if got := SummarizeTaxes(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("SummarizeTaxes = %v, want %v", got, want)
+	}
+}
@@ -1,40 +1,322 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
 
+// Status transaksi
+const (
+	TransactionStatusCompleted         = "completed"
+	TransactionStatusPartiallyRefunded = "partially_refunded"
+	TransactionStatusRefunded          = "refunded"
+	TransactionStatusVoided            = "voided"
+)
+
+// Transaction - GrossAmount adalah jumlah nilai kotor semua line, PromotionAmount total potongan promo otomatis,
+// DiscountAmount total diskon manual line dan keranjang, VoucherAmount potongan voucher, dan
+// SubtotalAmount = GrossAmount - PromotionAmount - DiscountAmount - VoucherAmount (harga jual bersih).
+// TaxBaseAmount adalah DPP (termasuk ServiceChargeAmount), TaxAmount pajaknya, dan
+// TotalAmount = TaxBaseAmount + TaxAmount (grand total yang dibayar). TaxInclusive mencatat apakah
+// harga jual sudah termasuk pajak saat checkout; Taxes rincian DPP dan pajak per kategori pajak.
+// RoundingAmount adalah pembulatan tunai (bisa negatif), jadi yang dibayar TotalAmount + RoundingAmount.
+// CartDiscountAmount adalah bagian diskon keranjang. CustomerRef adalah identitas pelanggan dari kasir (no. HP/member).
 type Transaction struct {
-	ID          int                 `json:"id"`
-	TotalAmount int                 `json:"total_amount"`
-	CreatedAt   time.Time           `json:"created_at"`
-	Details     []TransactionDetail `json:"details"`
+	ID                  int                 `json:"id"`
+	OutletID            int                 `json:"outlet_id"`
+	CustomerRef         string              `json:"customer_ref,omitempty"`
+	GrossAmount         int                 `json:"gross_amount"`
+	PromotionAmount     int                 `json:"promotion_amount"`
+	DiscountAmount      int                 `json:"discount_amount"`
+	CartDiscount        *Discount           `json:"cart_discount,omitempty"`
+	CartDiscountAmount  int                 `json:"cart_discount_amount"`
+	VoucherCode         string              `json:"voucher_code,omitempty"`
+	VoucherAmount       int                 `json:"voucher_amount"`
+	SubtotalAmount      int                 `json:"subtotal_amount"`
+	TaxInclusive        bool                `json:"tax_inclusive"`
+	TaxBaseAmount       int                 `json:"tax_base_amount"`
+	ServiceChargeAmount int                 `json:"service_charge_amount"`
+	TaxAmount           int                 `json:"tax_amount"`
+	TotalAmount         int                 `json:"total_amount"`
+	RoundingAmount      int                 `json:"rounding_amount"`
+	TotalPaid           int                 `json:"total_paid"`
+	Change              int                 `json:"kembalian"`
+	Status              string              `json:"status"`
+	CreatedAt           time.Time           `json:"created_at"`
+	Details             []TransactionDetail `json:"details"`
+	Taxes               []TaxSummary        `json:"taxes"`
+	Promotions          []AppliedPromotion  `json:"promotions"`
+	Payments            []Payment           `json:"payments"`
+	Refunds             []Refund            `json:"refunds,omitempty"`
 }
 
+// TransactionDetail - ProductName, SKU, UnitPrice dan UnitCost adalah snapshot saat checkout,
+// jadi riwayat tidak berubah kalau produk di-rename atau dihapus (ProductID jadi 0).
+// GrossAmount adalah harga x quantity, PromotionAmount potongan promo, DiscountAmount diskon line ini,
+// CartDiscountAmount dan VoucherAmount bagian diskon keranjang dan voucher untuk line ini, dan Subtotal
+// nilai bersihnya. TaxCategory dan TaxRate adalah snapshot saat checkout, TaxBaseAmount DPP line
+// (termasuk bagian ServiceChargeAmount), TaxAmount pajaknya, dan TotalAmount = TaxBaseAmount + TaxAmount
+// yang dibayar untuk line ini (dipakai refund).
 type TransactionDetail struct {
-	ID            int    `json:"id"`
-	TransactionID int    `json:"transaction_id"`
-	ProductID     int    `json:"product_id"`
-	ProductName   string `json:"product_name,omitempty"`
-	Quantity      int    `json:"quantity"`
-	Subtotal      int    `json:"subtotal"`
+	ID                  int       `json:"id"`
+	TransactionID       int       `json:"transaction_id"`
+	ProductID           int       `json:"product_id"`
+	ProductName         string    `json:"product_name,omitempty"`
+	SKU                 string    `json:"sku,omitempty"`
+	UnitPrice           int       `json:"unit_price"`
+	UnitCost            int       `json:"unit_cost"`
+	Quantity            float64   `json:"quantity"`
+	GrossAmount         int       `json:"gross_amount"`
+	PromotionAmount     int       `json:"promotion_amount"`
+	Discount            *Discount `json:"discount,omitempty"`
+	DiscountAmount      int       `json:"discount_amount"`
+	CartDiscountAmount  int       `json:"cart_discount_amount"`
+	VoucherAmount       int       `json:"voucher_amount"`
+	Subtotal            int       `json:"subtotal"`
+	TaxCategory         string    `json:"tax_category"`
+	TaxRate             float64   `json:"tax_rate"`
+	TaxBaseAmount       int       `json:"tax_base_amount"`
+	ServiceChargeAmount int       `json:"service_charge_amount"`
+	TaxAmount           int       `json:"tax_amount"`
+	TotalAmount         int       `json:"total_amount"`
+	// Batches - batch yang dipakai line ini (FEFO), hanya untuk produk batch-tracked
+	Batches []BatchAllocation `json:"batches,omitempty"`
+}
+
+// Jenis refund: void membatalkan seluruh sisa transaksi, refund hanya line tertentu
+const (
+	RefundTypeVoid   = "void"
+	RefundTypeRefund = "refund"
+)
+
+// Refund - Amount adalah nilai line yang dikembalikan, termasuk TaxAmount (pajak yang ikut dikembalikan).
+// RoundingAmount membalik pembulatan tunai transaksi dan hanya diisi pada refund yang menghabiskan
+// transaksi (void atau refund terakhir). Payments adalah rincian pengembalian per metode pembayaran,
+// jumlahnya sama dengan Amount + RoundingAmount.
+type Refund struct {
+	ID             int             `json:"id"`
+	TransactionID  int             `json:"transaction_id"`
+	Type           string          `json:"type"`
+	Reason         string          `json:"reason"`
+	Amount         int             `json:"amount"`
+	TaxAmount      int             `json:"tax_amount"`
+	RoundingAmount int             `json:"rounding_amount"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Items          []RefundItem    `json:"items"`
+	Payments       []RefundPayment `json:"payments"`
+}
+
+// RefundItem - Amount, TaxAmount dan ServiceChargeAmount proporsional terhadap total line yang di-refund
+type RefundItem struct {
+	ID                  int     `json:"id"`
+	RefundID            int     `json:"refund_id"`
+	TransactionDetailID int     `json:"transaction_detail_id"`
+	ProductID           int     `json:"product_id"`
+	Quantity            float64 `json:"quantity"`
+	Amount              int     `json:"amount"`
+	TaxAmount           int     `json:"tax_amount"`
+	ServiceChargeAmount int     `json:"service_charge_amount"`
+}
+
+type VoidRequest struct {
+	Reason string `json:"reason"`
+}
+
+type RefundRequestItem struct {
+	TransactionDetailID int     `json:"transaction_detail_id"`
+	Quantity            float64 `json:"quantity"`
+}
+
+type RefundRequest struct {
+	Reason string              `json:"reason"`
+	Items  []RefundRequestItem `json:"items"`
 }
 
+var (
+	ErrTransactionNotFound = errors.New("transaksi tidak ditemukan")
+	ErrRefundNotAllowed    = errors.New("refund not allowed")
+	// ErrInvalidQuantity - quantity tidak sesuai satuan produk (mis. desimal untuk pcs)
+	ErrInvalidQuantity = errors.New("invalid quantity")
+)
+
+// TransactionFilter - filter untuk GET /api/transactions. Field kosong/nil berarti tidak difilter.
+type TransactionFilter struct {
+	Page      int
+	Limit     int
+	StartDate string
+	EndDate   string
+	MinTotal  *int
+	MaxTotal  *int
+	ProductID int
+	OutletID  int
+}
+
+type TransactionList struct {
+	Data       []Transaction `json:"data"`
+	Pagination Pagination    `json:"pagination"`
+}
+
+// CheckoutItem - produk bisa direferensikan lewat product_id atau barcode (hasil scan).
+// Quantity boleh desimal untuk produk timbangan (kg, liter).
+// FixedSubtotal diisi service dari label timbangan yang memuat harga, bukan dari client.
+// Discount opsional, dihitung dari nilai line setelah potongan promo.
 type CheckoutItem struct {
-	ProductID int `json:"product_id"`
-	Quantity  int `json:"quantity"`
+	ProductID     int       `json:"product_id"`
+	Barcode       string    `json:"barcode,omitempty"`
+	Quantity      float64   `json:"quantity"`
+	Discount      *Discount `json:"discount,omitempty"`
+	FixedSubtotal *int      `json:"-"`
 }
 
+// CheckoutRequest - OutletID 0 berarti outlet default, stok dipotong dari outlet ini.
+// Discount adalah diskon keranjang, dihitung dari total setelah promo dan diskon line.
+// Role menentukan batas diskon (kosong berarti DefaultDiscountRole). Role tidak dibaca dari body, tapi diisi
+// handler dari header ROLE_HEADER yang dipasang gateway setelah autentikasi; MaxDiscountPercent diisi service
+// dari konfigurasi. VoucherCode dipotong terakhir, CustomerRef wajib untuk voucher
+// yang dibatasi per pelanggan. Tax dan CashRounding juga diisi service dari konfigurasi.
 type CheckoutRequest struct {
-	Items   []CheckoutItem `json:"items"`
+	OutletID           int            `json:"outlet_id"`
+	Role               string         `json:"-"`
+	CustomerRef        string         `json:"customer_ref,omitempty"`
+	Items              []CheckoutItem `json:"items"`
+	Discount           *Discount      `json:"discount,omitempty"`
+	VoucherCode        string         `json:"voucher_code,omitempty"`
+	Payments           []PaymentInput `json:"payments"`
+	MaxDiscountPercent float64        `json:"-"`
+	Tax                TaxConfig      `json:"-"`
+	CashRounding       CashRounding   `json:"-"`
+}
+
+// CartPrice - hasil hitung harga cart tanpa checkout (dry-run), angkanya sama dengan yang akan
+// tersimpan di Transaction kalau cart di-checkout saat itu juga. Stok tidak dicek.
+// CashRoundingAmount adalah pembulatan kalau seluruh total dibayar tunai.
+type CartPrice struct {
+	OutletID            int                 `json:"outlet_id"`
+	GrossAmount         int                 `json:"gross_amount"`
+	PromotionAmount     int                 `json:"promotion_amount"`
+	DiscountAmount      int                 `json:"discount_amount"`
+	CartDiscountAmount  int                 `json:"cart_discount_amount"`
+	VoucherCode         string              `json:"voucher_code,omitempty"`
+	VoucherAmount       int                 `json:"voucher_amount"`
+	SubtotalAmount      int                 `json:"subtotal_amount"`
+	TaxInclusive        bool                `json:"tax_inclusive"`
+	TaxBaseAmount       int                 `json:"tax_base_amount"`
+	ServiceChargeAmount int                 `json:"service_charge_amount"`
+	TaxAmount           int                 `json:"tax_amount"`
+	TotalAmount         int                 `json:"total_amount"`
+	CashRoundingAmount  int                 `json:"cash_rounding_amount"`
+	Items               []TransactionDetail `json:"items"`
+	Taxes               []TaxSummary        `json:"taxes"`
+	Promotions          []AppliedPromotion  `json:"promotions"`
+}
+
+// IdempotencyKey - key dari header Idempotency-Key untuk checkout.
+// Replayed di-set oleh repository kalau response diambil dari checkout sebelumnya.
+type IdempotencyKey struct {
+	Key         string
+	RequestHash string
+	ExpiresAt   time.Time
+	Replayed    bool
+}
+
+// ErrIdempotencyKeyReused - key yang sama dipakai untuk body request yang berbeda
+var ErrIdempotencyKeyReused = errors.New("idempotency key already used with a different request body")
+
+// StockShortage - satu item checkout yang melebihi stok tersedia
+type StockShortage struct {
+	ProductID int     `json:"product_id"`
+	Requested float64 `json:"requested"`
+	Available float64 `json:"available"`
+}
+
+// InsufficientStockError - checkout ditolak karena stok tidak cukup
+type InsufficientStockError struct {
+	Items []StockShortage `json:"items"`
+}
+
+func (e *InsufficientStockError) Error() string {
+	parts := make([]string, 0, len(e.Items))
+	for _, item := range e.Items {
+		parts = append(parts, fmt.Sprintf("product id %d (requested %g, available %g)", item.ProductID, item.Requested, item.Available))
+	}
+	return "insufficient stock: " + strings.Join(parts, ", ")
 }
 
 // Sales Report Models
+// TotalRevenue adalah omzet kotor (gross, termasuk pajak dan service charge), NetRevenue = TotalRevenue - TotalRefund.
+// Refund dihitung berdasarkan tanggal refund, bukan tanggal transaksi asal.
+// TotalTax dan TotalServiceCharge adalah pajak dan service charge yang terkumpul setelah dikurangi refund,
+// rinciannya per kategori pajak di Taxes.
+// TotalRounding adalah jumlah pembulatan tunai dikurangi pembulatan yang dibalik refund, terpisah dari omzet;
+// uang bersih di laci dan rekening =
+// NetRevenue + TotalRounding (sama dengan jumlah NetRevenue di PerMetode).
+// COGS (HPP) dan GrossProfit dihitung dari snapshot unit_cost, juga sudah dikurangi refund. Pajak tidak termasuk laba.
+// OutletID nil berarti gabungan semua outlet, rinciannya ada di PerOutlet.
 type SalesSummary struct {
-	TotalRevenue   int            `json:"total_revenue"`
-	TotalTransaksi int            `json:"total_transaksi"`
-	ProdukTerlaris *BestSeller    `json:"produk_terlaris"`
+	OutletID           *int                   `json:"outlet_id"`
+	TotalRevenue       int                    `json:"total_revenue"`
+	TotalRefund        int                    `json:"total_refund"`
+	NetRevenue         int                    `json:"net_revenue"`
+	TotalRounding      int                    `json:"total_rounding"`
+	TotalTax           int                    `json:"total_tax"`
+	TotalServiceCharge int                    `json:"total_service_charge"`
+	Taxes              []TaxSummary           `json:"taxes"`
+	TotalTransaksi     int                    `json:"total_transaksi"`
+	ProdukTerlaris     *BestSeller            `json:"produk_terlaris"`
+	PerMetode          []PaymentMethodSummary `json:"metode_pembayaran"`
+	COGS               int                    `json:"cogs"`
+	GrossProfit        int                    `json:"gross_profit"`
+	MarginPersen       float64                `json:"margin_persen"`
+	ProfitProduk       []ProductProfit        `json:"profit_per_produk"`
+	PerOutlet          []OutletSalesSummary   `json:"per_outlet,omitempty"`
+}
+
+// OutletSalesSummary - omzet satu outlet dalam periode laporan
+type OutletSalesSummary struct {
+	OutletID       int    `json:"outlet_id"`
+	OutletName     string `json:"outlet_name"`
+	TotalRevenue   int    `json:"total_revenue"`
+	TotalRefund    int    `json:"total_refund"`
+	NetRevenue     int    `json:"net_revenue"`
+	TotalRounding  int    `json:"total_rounding"`
+	TotalTransaksi int    `json:"total_transaksi"`
+}
+
+// ProductProfit - laba kotor per produk, sudah dikurangi refund dalam periode yang sama.
+// Revenue tidak termasuk pajak.
+type ProductProfit struct {
+	ProductID    int     `json:"product_id"`
+	Nama         string  `json:"nama"`
+	QtyTerjual   float64 `json:"qty_terjual"`
+	Revenue      int     `json:"revenue"`
+	COGS         int     `json:"cogs"`
+	GrossProfit  int     `json:"gross_profit"`
+	MarginPersen float64 `json:"margin_persen"`
+}
+
+// MarginPercentage - laba kotor dibanding omzet, dibulatkan 2 desimal
+func MarginPercentage(grossProfit, revenue int) float64 {
+	if revenue == 0 {
+		return 0
+	}
+	return math.Round(float64(grossProfit)/float64(revenue)*10000) / 100
+}
+
+// PaymentMethodSummary - omzet per metode pembayaran (nilai yang dipakai, sudah dikurangi kembalian,
+// termasuk pembulatan tunai). TotalRefund adalah refund yang dikembalikan lewat metode ini dalam periode
+// (berdasarkan tanggal refund) dan NetRevenue = TotalRevenue - TotalRefund.
+type PaymentMethodSummary struct {
+	Metode         string `json:"metode"`
+	TotalRevenue   int    `json:"total_revenue"`
+	TotalRefund    int    `json:"total_refund"`
+	NetRevenue     int    `json:"net_revenue"`
+	TotalTransaksi int    `json:"total_transaksi"`
 }
 
 type BestSeller struct {
-	Nama       string `json:"nama"`
-	QtyTerjual int    `json:"qty_terjual"`
+	Nama       string  `json:"nama"`
+	QtyTerjual float64 `json:"qty_terjual"`
 }
\ No newline at end of file
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Jenis nilai voucher, sama dengan jenis diskon manual
+const (
+	VoucherTypePercent = "percent"
+	VoucherTypeFixed   = "fixed"
+)
+
+var (
+	ErrVoucherNotFound  = errors.New("voucher not found")
+	ErrDuplicateVoucher = errors.New("voucher code is already used")
+	ErrVoucherArchived  = errors.New("voucher is archived")
+	// ErrVoucherNotApplicable - voucher belum/tidak lagi berlaku atau belanja belum mencapai minimum
+	ErrVoucherNotApplicable = errors.New("voucher cannot be applied")
+	// ErrVoucherLimitReached - kuota total atau kuota per pelanggan sudah habis
+	ErrVoucherLimitReached = errors.New("voucher usage limit reached")
+	// ErrCustomerRequired - voucher dengan batas per pelanggan butuh customer_ref di checkout
+	ErrCustomerRequired = errors.New("customer_ref is required for this voucher")
+)
+
+// Voucher - kode yang ditukarkan pelanggan saat checkout. Code disimpan huruf besar dan unik.
+// Value persen (0-100) untuk percent dengan MaxDiscount opsional sebagai batas rupiah, atau rupiah untuk fixed.
+// MinSpend dibandingkan dengan total setelah promo dan diskon manual. StartDate/EndDate (YYYY-MM-DD) opsional.
+// UsageLimit dan PerCustomerLimit 0 berarti tanpa batas. UsedCount dihitung dari penukaran yang masih berlaku.
+type Voucher struct {
+	ID               int        `json:"id"`
+	Code             string     `json:"code"`
+	Description      string     `json:"description"`
+	Type             string     `json:"type"`
+	Value            float64    `json:"value"`
+	MaxDiscount      int        `json:"max_discount"`
+	MinSpend         int        `json:"min_spend"`
+	StartDate        *string    `json:"start_date"`
+	EndDate          *string    `json:"end_date"`
+	UsageLimit       int        `json:"usage_limit"`
+	PerCustomerLimit int        `json:"per_customer_limit"`
+	UsedCount        int        `json:"used_count"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ArchivedAt       *time.Time `json:"archived_at,omitempty"`
+}
+
+// NormalizeVoucherCode - kode tidak membedakan huruf besar/kecil dan spasi di pinggir
+func NormalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CheckApplicable - cek status arsip, masa berlaku pada tanggal at dan minimum belanja
+func (v *Voucher) CheckApplicable(at time.Time, spend int) error {
+	if v.ArchivedAt != nil {
+		return fmt.Errorf("%w: %s", ErrVoucherArchived, v.Code)
+	}
+	date := at.Format("2006-01-02")
+	if v.StartDate != nil && date < *v.StartDate {
+		return fmt.Errorf("%w: %s is valid from %s", ErrVoucherNotApplicable, v.Code, *v.StartDate)
+	}
+	if v.EndDate != nil && date > *v.EndDate {
+		return fmt.Errorf("%w: %s expired on %s", ErrVoucherNotApplicable, v.Code, *v.EndDate)
+	}
+	if spend < v.MinSpend {
+		return fmt.Errorf("%w: %s needs a minimum spend of %d, cart total is %d", ErrVoucherNotApplicable, v.Code, v.MinSpend, spend)
+	}
+	return nil
+}
+
+// Amount - potongan voucher untuk base, tidak pernah melebihi base
+func (v *Voucher) Amount(base int) int {
+	amount := int(v.Value)
+	if v.Type == VoucherTypePercent {
+		amount = int(math.Round(float64(base) * v.Value / 100))
+		if v.MaxDiscount > 0 && amount > v.MaxDiscount {
+			amount = v.MaxDiscount
+		}
+	}
+	if amount > base {
+		amount = base
+	}
+	return amount
+}
+
+// VoucherUsage - ringkasan penukaran satu voucher dalam periode laporan.
+// Sales adalah total yang dibayar pada transaksi yang memakai voucher ini.
+type VoucherUsage struct {
+	VoucherID       int    `json:"voucher_id"`
+	Code            string `json:"code"`
+	Redemptions     int    `json:"redemptions"`
+	UniqueCustomers int    `json:"unique_customers"`
+	DiscountAmount  int    `json:"discount_amount"`
+	Sales           int    `json:"sales"`
+}
+
+// VoucherUsageReport - penukaran voucher (yang tidak dibatalkan) per tanggal transaksi, OutletID nil berarti semua outlet
+type VoucherUsageReport struct {
+	StartDate        string         `json:"start_date"`
+	EndDate          string         `json:"end_date"`
+	OutletID         *int           `json:"outlet_id"`
+	TotalRedemptions int            `json:"total_redemptions"`
+	TotalDiscount    int            `json:"total_discount"`
+	Vouchers         []VoucherUsage `json:"vouchers"`
+}
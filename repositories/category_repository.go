@@ -2,7 +2,7 @@ package repositories
 
 import (
 	"database/sql"
-	"errors"
+	"fmt"
 	"kasir-api/models"
 )
 
@@ -14,8 +14,13 @@ func NewCategoryRepository(db *sql.DB) *CategoryRepository {
 	return &CategoryRepository{db: db}
 }
 
-func (repo *CategoryRepository) GetAll() ([]models.Category, error) {
-	query := "SELECT id, name, description FROM category"
+// GetAll - category yang diarsipkan hanya ikut kalau includeArchived true
+func (repo *CategoryRepository) GetAll(includeArchived bool) ([]models.Category, error) {
+	query := "SELECT id, name, description, archived_at FROM category"
+	if !includeArchived {
+		query += " WHERE archived_at IS NULL"
+	}
+	query += " ORDER BY id"
 	rows, err := repo.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -25,7 +30,7 @@ func (repo *CategoryRepository) GetAll() ([]models.Category, error) {
 	categories := make([]models.Category, 0)
 	for rows.Next() {
 		var c models.Category
-		err := rows.Scan(&c.ID, &c.Name, &c.Description)
+		err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.ArchivedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -43,12 +48,12 @@ func (repo *CategoryRepository) Create(category *models.Category) error {
 
 // GetByID - ambil category by ID
 func (repo *CategoryRepository) GetByID(id int) (*models.Category, error) {
-	query := "SELECT id, name, description FROM category WHERE id = $1"
+	query := "SELECT id, name, description, archived_at FROM category WHERE id = $1"
 
 	var c models.Category
-	err := repo.db.QueryRow(query, id).Scan(&c.ID, &c.Name, &c.Description)
+	err := repo.db.QueryRow(query, id).Scan(&c.ID, &c.Name, &c.Description, &c.ArchivedAt)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("category tidak ditemukan")
+		return nil, models.ErrCategoryNotFound
 	}
 	if err != nil {
 		return nil, err
@@ -70,15 +75,70 @@ func (repo *CategoryRepository) Update(category *models.Category) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("category tidak ditemukan")
+		return models.ErrCategoryNotFound
 	}
 
 	return nil
 }
 
-func (repo *CategoryRepository) Delete(id int) error {
-	query := "DELETE FROM category WHERE id = $1"
-	result, err := repo.db.Exec(query, id)
+// Delete - arsipkan category (soft delete). Kalau masih ada produk aktif di category ini,
+// delete ditolak (ErrCategoryInUse) kecuali reassignTo diisi: produk dipindah dulu ke category tujuan.
+func (repo *CategoryRepository) Delete(id int, reassignTo *int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow("SELECT id FROM category WHERE id = $1 FOR UPDATE", id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return models.ErrCategoryNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var productCount int
+	err = tx.QueryRow("SELECT COUNT(*) FROM products WHERE category_id = $1 AND archived_at IS NULL", id).Scan(&productCount)
+	if err != nil {
+		return err
+	}
+
+	if productCount > 0 {
+		if reassignTo == nil {
+			return fmt.Errorf("%w (%d produk), isi reassign_to untuk memindahkan produk", models.ErrCategoryInUse, productCount)
+		}
+
+		if *reassignTo == id {
+			return fmt.Errorf("%w: tidak bisa reassign ke category yang sama", models.ErrInvalidReassignTarget)
+		}
+		// FOR SHARE supaya category tujuan tidak diarsipkan di tx lain sebelum produk dipindahkan
+		err = tx.QueryRow("SELECT id FROM category WHERE id = $1 AND archived_at IS NULL FOR SHARE", *reassignTo).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: category %d tidak ditemukan", models.ErrInvalidReassignTarget, *reassignTo)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE products SET category_id = $1 WHERE category_id = $2 AND archived_at IS NULL", *reassignTo, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE category SET archived_at = COALESCE(archived_at, NOW()) WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Restore - aktifkan kembali category yang diarsipkan
+func (repo *CategoryRepository) Restore(id int) error {
+	result, err := repo.db.Exec("UPDATE category SET archived_at = NULL WHERE id = $1", id)
 	if err != nil {
 		return err
 	}
@@ -89,8 +149,8 @@ func (repo *CategoryRepository) Delete(id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("category tidak ditemukan")
+		return models.ErrCategoryNotFound
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/models"
+)
+
+type OutletRepository struct {
+	db *sql.DB
+}
+
+func NewOutletRepository(db *sql.DB) *OutletRepository {
+	return &OutletRepository{db: db}
+}
+
+// GetAll - outlet yang diarsipkan hanya ikut kalau includeArchived true
+func (repo *OutletRepository) GetAll(includeArchived bool) ([]models.Outlet, error) {
+	query := "SELECT id, name, COALESCE(address, ''), COALESCE(phone, ''), archived_at FROM outlets"
+	if !includeArchived {
+		query += " WHERE archived_at IS NULL"
+	}
+	query += " ORDER BY id"
+	rows, err := repo.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	outlets := make([]models.Outlet, 0)
+	for rows.Next() {
+		var o models.Outlet
+		err := rows.Scan(&o.ID, &o.Name, &o.Address, &o.Phone, &o.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+		outlets = append(outlets, o)
+	}
+
+	return outlets, rows.Err()
+}
+
+func (repo *OutletRepository) Create(outlet *models.Outlet) error {
+	query := "INSERT INTO outlets (name, address, phone) VALUES ($1, $2, $3) RETURNING id"
+	return repo.db.QueryRow(query, outlet.Name, nullIfEmpty(outlet.Address), nullIfEmpty(outlet.Phone)).Scan(&outlet.ID)
+}
+
+// GetByID - ambil outlet by ID, termasuk yang diarsipkan
+func (repo *OutletRepository) GetByID(id int) (*models.Outlet, error) {
+	query := "SELECT id, name, COALESCE(address, ''), COALESCE(phone, ''), archived_at FROM outlets WHERE id = $1"
+
+	var o models.Outlet
+	err := repo.db.QueryRow(query, id).Scan(&o.ID, &o.Name, &o.Address, &o.Phone, &o.ArchivedAt)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrOutletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+func (repo *OutletRepository) Update(outlet *models.Outlet) error {
+	query := "UPDATE outlets SET name = $1, address = $2, phone = $3 WHERE id = $4 RETURNING archived_at"
+	err := repo.db.QueryRow(query, outlet.Name, nullIfEmpty(outlet.Address), nullIfEmpty(outlet.Phone), outlet.ID).Scan(&outlet.ArchivedAt)
+	if err == sql.ErrNoRows {
+		return models.ErrOutletNotFound
+	}
+	return err
+}
+
+// Delete - arsipkan outlet (soft delete), transaksi dan stok lama tetap menunjuk ke outlet ini
+func (repo *OutletRepository) Delete(id int) error {
+	result, err := repo.db.Exec("UPDATE outlets SET archived_at = COALESCE(archived_at, NOW()) WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrOutletNotFound
+	}
+
+	return nil
+}
+
+// Restore - aktifkan kembali outlet yang diarsipkan
+func (repo *OutletRepository) Restore(id int) error {
+	result, err := repo.db.Exec("UPDATE outlets SET archived_at = NULL WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrOutletNotFound
+	}
+
+	return nil
+}
+
+// resolveOutlet - outletID 0 berarti outlet default (outlet aktif dengan id terkecil).
+// Outlet yang tidak ada atau diarsipkan ditolak.
+func resolveOutlet(tx *sql.Tx, outletID int) (int, error) {
+	if outletID == 0 {
+		err := tx.QueryRow("SELECT id FROM outlets WHERE archived_at IS NULL ORDER BY id LIMIT 1").Scan(&outletID)
+		if err == sql.ErrNoRows {
+			return 0, models.ErrOutletNotFound
+		}
+		return outletID, err
+	}
+
+	var archived bool
+	err := tx.QueryRow("SELECT archived_at IS NOT NULL FROM outlets WHERE id = $1", outletID).Scan(&archived)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: outlet id %d", models.ErrOutletNotFound, outletID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if archived {
+		return 0, fmt.Errorf("%w: outlet id %d", models.ErrOutletArchived, outletID)
+	}
+	return outletID, nil
+}
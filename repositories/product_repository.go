@@ -3,7 +3,13 @@ package repositories
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"kasir-api/models"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type ProductRepository struct {
@@ -14,92 +20,403 @@ func NewProductRepository(db *sql.DB) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
-func (repo *ProductRepository) GetAll() ([]models.Product, error) {
-	query := "SELECT id, name, price, stock FROM products"
-	rows, err := repo.db.Query(query)
+// GetAll - ambil produk sesuai filter, sort dan pagination. Total adalah jumlah produk
+// yang cocok dengan filter sebelum pagination.
+func (repo *ProductRepository) GetAll(filter models.ProductFilter) ([]models.Product, int, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	// Dengan OutletID stok yang difilter, diurutkan dan dikembalikan adalah stok outlet itu, bukan total semua outlet
+	stock := "stock"
+	from := "products"
+	if filter.OutletID != 0 {
+		args = append(args, filter.OutletID)
+		stock = "COALESCE(os.stock, 0)"
+		from = fmt.Sprintf("products LEFT JOIN outlet_stocks os ON os.product_id = products.id AND os.outlet_id = $%d", len(args))
+	}
+
+	if filter.Name != "" {
+		addCondition("(LOWER(name) LIKE LOWER($%[1]d) OR LOWER(sku) LIKE LOWER($%[1]d))", "%"+filter.Name+"%")
+	}
+	if filter.CategoryID != 0 {
+		addCondition("category_id = $%d", filter.CategoryID)
+	}
+	if filter.MinPrice != nil {
+		addCondition("price >= $%d", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		addCondition("price <= $%d", *filter.MaxPrice)
+	}
+	if filter.InStock {
+		conditions = append(conditions, stock+" > 0")
+	}
+	if filter.LowStock {
+		conditions = append(conditions, "min_stock > 0 AND "+stock+" <= min_stock")
+	}
+	if !filter.IncludeArchived {
+		conditions = append(conditions, "archived_at IS NULL")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM "+from+where, args...).Scan(&total)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	// Kolom sort diambil dari whitelist, bukan langsung dari input
+	orderBy := "id"
+	direction := "ASC"
+	sort := filter.Sort
+	if strings.HasPrefix(sort, "-") {
+		direction = "DESC"
+		sort = strings.TrimPrefix(sort, "-")
+	}
+	if column, ok := models.ProductSortColumns[sort]; ok {
+		orderBy = column
+	}
+	if orderBy == "stock" {
+		orderBy = stock
+	}
+
+	query := fmt.Sprintf("SELECT id, COALESCE(sku, ''), COALESCE(plu, ''), name, unit, price, cost_price, %s, min_stock, reorder_qty, batch_tracked, tax_category, category_id, archived_at FROM %s%s ORDER BY %s %s, id %s",
+		stock, from, where, orderBy, direction, direction)
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+		args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+	}
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	products := make([]models.Product, 0)
+	ids := make([]int, 0)
 	for rows.Next() {
 		var p models.Product
-		err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock)
+		err := rows.Scan(&p.ID, &p.SKU, &p.PLU, &p.Name, &p.Unit, &p.Price, &p.CostPrice, &p.Stock, &p.MinStock, &p.ReorderQty, &p.BatchTracked, &p.TaxCategory, &p.CategoryID, &p.ArchivedAt)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		products = append(products, p)
+		ids = append(ids, p.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
 	}
 
-	return products, nil
+	barcodes, err := repo.getBarcodes(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range products {
+		products[i].Barcodes = barcodes[products[i].ID]
+	}
+
+	return products, total, nil
 }
 
-func (repo *ProductRepository) SearchByName(name string) ([]models.Product, error) {
-	query := "SELECT id, name, price, stock FROM products WHERE LOWER(name) LIKE LOWER($1)"
-	rows, err := repo.db.Query(query, "%"+name+"%")
+// getBarcodes - ambil barcode untuk beberapa produk sekaligus, dikelompokkan per product id
+func (repo *ProductRepository) getBarcodes(productIDs []int) (map[int][]string, error) {
+	result := make(map[int][]string)
+	for _, id := range productIDs {
+		result[id] = make([]string, 0)
+	}
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders, args := inPlaceholders(productIDs)
+	rows, err := repo.db.Query("SELECT product_id, barcode FROM product_barcodes WHERE product_id IN ("+placeholders+") ORDER BY id", args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	products := make([]models.Product, 0)
 	for rows.Next() {
-		var p models.Product
-		err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock)
-		if err != nil {
+		var productID int
+		var barcode string
+		if err := rows.Scan(&productID, &barcode); err != nil {
 			return nil, err
 		}
-		products = append(products, p)
+		result[productID] = append(result[productID], barcode)
 	}
 
-	return products, nil
+	return result, rows.Err()
 }
 
-func (repo *ProductRepository) Create(product *models.Product) error {
-	query := "INSERT INTO products (name, price, stock) VALUES ($1, $2, $3) RETURNING id"
-	err := repo.db.QueryRow(query, product.Name, product.Price, product.Stock).Scan(&product.ID)
+// saveBarcodes - ganti seluruh barcode produk dengan daftar yang baru
+func saveBarcodes(tx *sql.Tx, productID int, barcodes []string) error {
+	_, err := tx.Exec("DELETE FROM product_barcodes WHERE product_id = $1", productID)
+	if err != nil {
+		return err
+	}
+
+	for _, barcode := range barcodes {
+		_, err = tx.Exec("INSERT INTO product_barcodes (product_id, barcode) VALUES ($1, $2)", productID, barcode)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mapUniqueViolation - ubah unique violation sku/plu/barcode jadi error yang bisa dibaca client
+func mapUniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
+	}
+
+	switch pgErr.ConstraintName {
+	case "product_barcodes_barcode_key":
+		return models.ErrDuplicateBarcode
+	case "products_sku_key":
+		return models.ErrDuplicateSKU
+	case "products_plu_key":
+		return models.ErrDuplicatePLU
+	}
 	return err
 }
 
-// GetByID - ambil produk by ID
+// nullIfEmpty - simpan string kosong sebagai NULL supaya tidak bentrok di unique index
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func (repo *ProductRepository) Create(product *models.Product) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Stok awal masuk ke outlet default lewat ledger supaya stock_movements selalu cocok dengan stok produk
+	// (untuk produk batch-tracked stok awal masuk ke DefaultBatchNumber)
+	query := "INSERT INTO products (sku, plu, name, unit, price, cost_price, stock, min_stock, reorder_qty, batch_tracked, tax_category, category_id) VALUES ($1, $2, $3, $4, $5, $6, 0, $7, $8, $9, $10, $11) RETURNING id"
+	err = tx.QueryRow(query, nullIfEmpty(product.SKU), nullIfEmpty(product.PLU), product.Name, product.Unit, product.Price, product.CostPrice,
+		product.MinStock, product.ReorderQty, product.BatchTracked, product.TaxCategory, product.CategoryID).Scan(&product.ID)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+
+	if err := saveBarcodes(tx, product.ID, product.Barcodes); err != nil {
+		return mapUniqueViolation(err)
+	}
+
+	if product.Stock != 0 {
+		outletID, err := resolveOutlet(tx, 0)
+		if err != nil {
+			return err
+		}
+		err = recordStockMovement(tx, &models.StockMovement{
+			ProductID: product.ID,
+			OutletID:  outletID,
+			Type:      models.StockMovementAdjustment,
+			Quantity:  product.Stock,
+			Reason:    "initial stock",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByID - ambil produk by ID beserta kategori dan barcode-nya
 func (repo *ProductRepository) GetByID(id int) (*models.Product, error) {
-	query := "SELECT id, name, price, stock FROM products WHERE id = $1"
+	query := `
+		SELECT p.id, COALESCE(p.sku, ''), COALESCE(p.plu, ''), p.name, p.unit, p.price, p.cost_price, p.stock, p.min_stock, p.reorder_qty, p.batch_tracked, p.tax_category, p.category_id, p.archived_at,
+			c.name, c.description, c.archived_at
+		FROM products p
+		LEFT JOIN category c ON p.category_id = c.id
+		WHERE p.id = $1`
 
 	var p models.Product
-	err := repo.db.QueryRow(query, id).Scan(&p.ID, &p.Name, &p.Price, &p.Stock)
+	var categoryName, categoryDescription sql.NullString
+	var categoryArchivedAt *time.Time
+	err := repo.db.QueryRow(query, id).Scan(&p.ID, &p.SKU, &p.PLU, &p.Name, &p.Unit, &p.Price, &p.CostPrice, &p.Stock, &p.MinStock, &p.ReorderQty, &p.BatchTracked, &p.TaxCategory, &p.CategoryID, &p.ArchivedAt,
+		&categoryName, &categoryDescription, &categoryArchivedAt)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("produk tidak ditemukan")
+		return nil, models.ErrProductNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
 
+	if p.CategoryID != nil {
+		p.Category = &models.Category{
+			ID:          *p.CategoryID,
+			Name:        categoryName.String,
+			Description: categoryDescription.String,
+			ArchivedAt:  categoryArchivedAt,
+		}
+	}
+
+	barcodes, err := repo.getBarcodes([]int{p.ID})
+	if err != nil {
+		return nil, err
+	}
+	p.Barcodes = barcodes[p.ID]
+
+	p.OutletStocks, err = repo.getOutletStocks(p.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &p, nil
 }
 
-func (repo *ProductRepository) Update(product *models.Product) error {
-	query := "UPDATE products SET name = $1, price = $2, stock = $3 WHERE id = $4"
-	result, err := repo.db.Exec(query, product.Name, product.Price, product.Stock, product.ID)
+// getOutletStocks - stok produk per outlet aktif, outlet tanpa baris outlet_stocks dianggap 0.
+// in_transit dari transfer yang sudah di-dispatch ke outlet itu tapi belum diterima.
+func (repo *ProductRepository) getOutletStocks(productID int) ([]models.OutletStock, error) {
+	rows, err := repo.db.Query(`
+		SELECT o.id, o.name, COALESCE(os.stock, 0), COALESCE(it.quantity, 0)
+		FROM outlets o
+		LEFT JOIN outlet_stocks os ON os.outlet_id = o.id AND os.product_id = $1
+		LEFT JOIN (
+			SELECT st.destination_outlet_id, SUM(sti.quantity) AS quantity
+			FROM stock_transfer_items sti
+			JOIN stock_transfers st ON st.id = sti.stock_transfer_id
+			WHERE sti.product_id = $1 AND st.status = $2
+			GROUP BY st.destination_outlet_id
+		) it ON it.destination_outlet_id = o.id
+		WHERE o.archived_at IS NULL OR os.stock <> 0 OR it.quantity > 0
+		ORDER BY o.id`, productID, models.StockTransferStatusInTransit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocks := make([]models.OutletStock, 0)
+	for rows.Next() {
+		var s models.OutletStock
+		if err := rows.Scan(&s.OutletID, &s.OutletName, &s.Stock, &s.InTransit); err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, s)
+	}
+
+	return stocks, rows.Err()
+}
+
+// GetByBarcode - cari produk aktif dari hasil scan barcode
+func (repo *ProductRepository) GetByBarcode(code string) (*models.Product, error) {
+	var productID int
+	err := repo.db.QueryRow(`
+		SELECT b.product_id
+		FROM product_barcodes b
+		JOIN products p ON b.product_id = p.id
+		WHERE b.barcode = $1 AND p.archived_at IS NULL`, code).Scan(&productID)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.GetByID(productID)
+}
+
+// GetByPLU - cari produk aktif dari PLU yang tertanam di label timbangan
+func (repo *ProductRepository) GetByPLU(plu string) (*models.Product, error) {
+	var productID int
+	err := repo.db.QueryRow("SELECT id FROM products WHERE plu = $1 AND archived_at IS NULL", plu).Scan(&productID)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.GetByID(productID)
+}
+
+// Update - stok tidak ikut di-update, perubahan stok harus lewat ledger (stock adjustment).
+// Kalau requestedStock diisi dan berbeda dengan stok sekarang, update ditolak dengan ErrStockNotEditable.
+// product.Stock diisi dengan stok yang tersimpan. Kalau batch_tracked baru dinyalakan,
+// stok tiap outlet dipindahkan ke DefaultBatchNumber.
+func (repo *ProductRepository) Update(product *models.Product, requestedStock *float64) error {
+	tx, err := repo.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
+	var stock float64
+	var batchTracked bool
+	err = tx.QueryRow("SELECT stock, batch_tracked FROM products WHERE id = $1 FOR UPDATE", product.ID).Scan(&stock, &batchTracked)
+	if err == sql.ErrNoRows {
+		return models.ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if requestedStock != nil && models.RoundQuantity(*requestedStock) != stock {
+		return fmt.Errorf("%w: current stock is %g", models.ErrStockNotEditable, stock)
+	}
+	if product.Unit == models.UnitPcs && stock != math.Trunc(stock) {
+		return fmt.Errorf("%w: current stock %g is fractional, adjust it before switching the unit to pcs", models.ErrInvalidQuantity, stock)
+	}
+	product.Stock = stock
+
+	query := "UPDATE products SET sku = $1, plu = $2, name = $3, unit = $4, price = $5, cost_price = $6, min_stock = $7, reorder_qty = $8, batch_tracked = $9, tax_category = $10, category_id = $11 WHERE id = $12"
+	_, err = tx.Exec(query, nullIfEmpty(product.SKU), nullIfEmpty(product.PLU), product.Name, product.Unit, product.Price, product.CostPrice,
+		product.MinStock, product.ReorderQty, product.BatchTracked, product.TaxCategory, product.CategoryID, product.ID)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+
+	if product.BatchTracked && !batchTracked {
+		if err := resetProductBatches(tx, product.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := saveBarcodes(tx, product.ID, product.Barcodes); err != nil {
+		return mapUniqueViolation(err)
+	}
+
+	return tx.Commit()
+}
+
+// Delete - arsipkan produk (soft delete) supaya riwayat transaksi tetap utuh
+func (repo *ProductRepository) Delete(id int) error {
+	query := "UPDATE products SET archived_at = COALESCE(archived_at, NOW()) WHERE id = $1"
+	result, err := repo.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
 	if rows == 0 {
-		return errors.New("produk tidak ditemukan")
+		return models.ErrProductNotFound
 	}
 
 	return nil
 }
 
-func (repo *ProductRepository) Delete(id int) error {
-	query := "DELETE FROM products WHERE id = $1"
-	result, err := repo.db.Exec(query, id)
+// Restore - aktifkan kembali produk yang diarsipkan
+func (repo *ProductRepository) Restore(id int) error {
+	result, err := repo.db.Exec("UPDATE products SET archived_at = NULL WHERE id = $1", id)
 	if err != nil {
 		return err
 	}
@@ -109,8 +426,56 @@ func (repo *ProductRepository) Delete(id int) error {
 	}
 
 	if rows == 0 {
-		return errors.New("produk tidak ditemukan")
+		return models.ErrProductNotFound
 	}
 
-	return err
+	return nil
+}
+
+// GetReorderCandidates - produk aktif yang dipantau (min_stock > 0) atau terjual dalam `days` hari terakhir,
+// beserta quantity terjual bersih (dikurangi refund/void)
+func (repo *ProductRepository) GetReorderCandidates(days, outletID int) ([]models.ReorderSuggestion, error) {
+	// Dengan outletID stok dan penjualan dibatasi ke outlet itu, tanpa outletID dipakai total semua outlet
+	stock := "p.stock"
+	outletJoin := ""
+	salesCondition := ""
+	args := []interface{}{days}
+	if outletID != 0 {
+		args = append(args, outletID)
+		stock = "COALESCE(os.stock, 0)"
+		outletJoin = "LEFT JOIN outlet_stocks os ON os.product_id = p.id AND os.outlet_id = $2"
+		salesCondition = " AND t.outlet_id = $2"
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT p.id, p.name, COALESCE(p.sku, ''), p.unit, `+stock+`, p.min_stock, p.reorder_qty, p.cost_price, COALESCE(s.qty, 0)
+		FROM products p
+		`+outletJoin+`
+		LEFT JOIN (
+			SELECT td.product_id, SUM(td.quantity - COALESCE(ri.qty, 0)) AS qty
+			FROM transaction_details td
+			JOIN transactions t ON t.id = td.transaction_id
+			LEFT JOIN (
+				SELECT transaction_detail_id, SUM(quantity) AS qty FROM refund_items GROUP BY transaction_detail_id
+			) ri ON ri.transaction_detail_id = td.id
+			WHERE t.created_at >= NOW() - $1 * INTERVAL '1 day'`+salesCondition+`
+			GROUP BY td.product_id
+		) s ON s.product_id = p.id
+		WHERE p.archived_at IS NULL AND (p.min_stock > 0 OR s.qty > 0)
+		ORDER BY p.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := make([]models.ReorderSuggestion, 0)
+	for rows.Next() {
+		var c models.ReorderSuggestion
+		if err := rows.Scan(&c.ProductID, &c.Name, &c.SKU, &c.Unit, &c.Stock, &c.MinStock, &c.ReorderQty, &c.CostPrice, &c.SoldQty); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
 }
@@ -0,0 +1,299 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+)
+
+type PromotionRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionRepository(db *sql.DB) *PromotionRepository {
+	return &PromotionRepository{db: db}
+}
+
+const promotionColumns = `id, name, COALESCE(description, ''), type, value, buy_quantity, free_quantity, bundle_quantity, bundle_price,
+	min_quantity, TO_CHAR(start_date, 'YYYY-MM-DD'), TO_CHAR(end_date, 'YYYY-MM-DD'), COALESCE(days_of_week, ''),
+	TO_CHAR(start_time, 'HH24:MI'), TO_CHAR(end_time, 'HH24:MI'), priority, created_at, archived_at`
+
+// GetAll - promo yang diarsipkan hanya ikut kalau includeArchived true
+func (repo *PromotionRepository) GetAll(includeArchived bool) ([]models.Promotion, error) {
+	condition := "archived_at IS NULL"
+	if includeArchived {
+		condition = "TRUE"
+	}
+	return queryPromotions(repo.db, condition)
+}
+
+// GetByID - ambil promo by ID, termasuk yang diarsipkan
+func (repo *PromotionRepository) GetByID(id int) (*models.Promotion, error) {
+	promotions, err := queryPromotions(repo.db, "id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(promotions) == 0 {
+		return nil, models.ErrPromotionNotFound
+	}
+	return &promotions[0], nil
+}
+
+func (repo *PromotionRepository) Create(promotion *models.Promotion) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkPromotionTargets(tx, promotion); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`INSERT INTO promotions (name, description, type, value, buy_quantity, free_quantity, bundle_quantity, bundle_price,
+			min_quantity, start_date, end_date, days_of_week, start_time, end_time, priority)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id, created_at`,
+		promotion.Name, nullIfEmpty(promotion.Description), promotion.Type, promotion.Value, promotion.BuyQuantity, promotion.FreeQuantity,
+		promotion.BundleQuantity, promotion.BundlePrice, promotion.MinQuantity, promotion.StartDate, promotion.EndDate,
+		nullIfEmpty(formatDaysOfWeek(promotion.DaysOfWeek)), promotion.StartTime, promotion.EndTime, promotion.Priority).
+		Scan(&promotion.ID, &promotion.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertPromotionTargets(tx, promotion); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update - ganti seluruh aturan promo, daftar produk/kategori ikut diganti
+func (repo *PromotionRepository) Update(promotion *models.Promotion) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkPromotionTargets(tx, promotion); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`UPDATE promotions SET name = $1, description = $2, type = $3, value = $4, buy_quantity = $5, free_quantity = $6,
+			bundle_quantity = $7, bundle_price = $8, min_quantity = $9, start_date = $10, end_date = $11, days_of_week = $12,
+			start_time = $13, end_time = $14, priority = $15
+		WHERE id = $16 RETURNING created_at, archived_at`,
+		promotion.Name, nullIfEmpty(promotion.Description), promotion.Type, promotion.Value, promotion.BuyQuantity, promotion.FreeQuantity,
+		promotion.BundleQuantity, promotion.BundlePrice, promotion.MinQuantity, promotion.StartDate, promotion.EndDate,
+		nullIfEmpty(formatDaysOfWeek(promotion.DaysOfWeek)), promotion.StartTime, promotion.EndTime, promotion.Priority, promotion.ID).
+		Scan(&promotion.CreatedAt, &promotion.ArchivedAt)
+	if err == sql.ErrNoRows {
+		return models.ErrPromotionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM promotion_products WHERE promotion_id = $1", promotion.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM promotion_categories WHERE promotion_id = $1", promotion.ID); err != nil {
+		return err
+	}
+	if err := insertPromotionTargets(tx, promotion); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete - arsipkan promo (soft delete), promo berhenti berlaku tapi riwayat transaksi tetap menunjuk ke promo ini
+func (repo *PromotionRepository) Delete(id int) error {
+	result, err := repo.db.Exec("UPDATE promotions SET archived_at = COALESCE(archived_at, NOW()) WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrPromotionNotFound
+	}
+
+	return nil
+}
+
+// Restore - aktifkan kembali promo yang diarsipkan
+func (repo *PromotionRepository) Restore(id int) error {
+	result, err := repo.db.Exec("UPDATE promotions SET archived_at = NULL WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrPromotionNotFound
+	}
+
+	return nil
+}
+
+// queryer - *sql.DB atau *sql.Tx, supaya query promo bisa ikut tx checkout
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryPromotions - ambil promo beserta produk dan kategorinya, urut prioritas (tertinggi dulu).
+// Dipakai juga oleh checkout (dengan tx-nya) untuk mengambil promo yang tidak diarsipkan.
+func queryPromotions(db queryer, condition string, args ...interface{}) ([]models.Promotion, error) {
+	rows, err := db.Query("SELECT "+promotionColumns+" FROM promotions WHERE "+condition+" ORDER BY priority DESC, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := make([]models.Promotion, 0)
+	ids := make([]int, 0)
+	for rows.Next() {
+		var p models.Promotion
+		var daysOfWeek string
+		err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Type, &p.Value, &p.BuyQuantity, &p.FreeQuantity, &p.BundleQuantity,
+			&p.BundlePrice, &p.MinQuantity, &p.StartDate, &p.EndDate, &daysOfWeek, &p.StartTime, &p.EndTime, &p.Priority,
+			&p.CreatedAt, &p.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+		if p.DaysOfWeek, err = parseDaysOfWeek(daysOfWeek); err != nil {
+			return nil, err
+		}
+		p.ProductIDs = make([]int, 0)
+		p.CategoryIDs = make([]int, 0)
+		ids = append(ids, p.ID)
+		promotions = append(promotions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return promotions, nil
+	}
+
+	placeholders, idArgs := inPlaceholders(ids)
+	products, err := queryPromotionTargets(db, "SELECT promotion_id, product_id FROM promotion_products WHERE promotion_id IN ("+placeholders+") ORDER BY product_id", idArgs...)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := queryPromotionTargets(db, "SELECT promotion_id, category_id FROM promotion_categories WHERE promotion_id IN ("+placeholders+") ORDER BY category_id", idArgs...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range promotions {
+		if ids, ok := products[promotions[i].ID]; ok {
+			promotions[i].ProductIDs = ids
+		}
+		if ids, ok := categories[promotions[i].ID]; ok {
+			promotions[i].CategoryIDs = ids
+		}
+	}
+
+	return promotions, nil
+}
+
+// queryPromotionTargets - id produk/kategori per promotion_id
+func queryPromotionTargets(db queryer, query string, args ...interface{}) (map[int][]int, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := make(map[int][]int)
+	for rows.Next() {
+		var promotionID, id int
+		if err := rows.Scan(&promotionID, &id); err != nil {
+			return nil, err
+		}
+		targets[promotionID] = append(targets[promotionID], id)
+	}
+	return targets, rows.Err()
+}
+
+// checkPromotionTargets - semua product_ids dan category_ids harus ada
+func checkPromotionTargets(tx *sql.Tx, promotion *models.Promotion) error {
+	if len(promotion.ProductIDs) > 0 {
+		placeholders, args := inPlaceholders(promotion.ProductIDs)
+		var count int
+		err := tx.QueryRow("SELECT COUNT(*) FROM products WHERE id IN ("+placeholders+")", args...).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count != len(promotion.ProductIDs) {
+			return fmt.Errorf("%w: product_ids contains an unknown product", models.ErrProductNotFound)
+		}
+	}
+
+	if len(promotion.CategoryIDs) > 0 {
+		placeholders, args := inPlaceholders(promotion.CategoryIDs)
+		var count int
+		err := tx.QueryRow("SELECT COUNT(*) FROM category WHERE id IN ("+placeholders+")", args...).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count != len(promotion.CategoryIDs) {
+			return fmt.Errorf("%w: category_ids contains an unknown category", models.ErrCategoryNotFound)
+		}
+	}
+
+	return nil
+}
+
+func insertPromotionTargets(tx *sql.Tx, promotion *models.Promotion) error {
+	for _, productID := range promotion.ProductIDs {
+		_, err := tx.Exec("INSERT INTO promotion_products (promotion_id, product_id) VALUES ($1, $2)", promotion.ID, productID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, categoryID := range promotion.CategoryIDs {
+		_, err := tx.Exec("INSERT INTO promotion_categories (promotion_id, category_id) VALUES ($1, $2)", promotion.ID, categoryID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatDaysOfWeek - simpan hari sebagai teks "0,6" (0 = Minggu)
+func formatDaysOfWeek(days []int) string {
+	parts := make([]string, len(days))
+	for i, d := range days {
+		parts[i] = strconv.Itoa(d)
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseDaysOfWeek(value string) ([]int, error) {
+	days := make([]int, 0)
+	if value == "" {
+		return days, nil
+	}
+	for _, part := range strings.Split(value, ",") {
+		d, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid days_of_week %q", value)
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
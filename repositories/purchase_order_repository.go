@@ -0,0 +1,522 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"kasir-api/models"
+)
+
+type PurchaseOrderRepository struct {
+	db *sql.DB
+}
+
+func NewPurchaseOrderRepository(db *sql.DB) *PurchaseOrderRepository {
+	return &PurchaseOrderRepository{db: db}
+}
+
+// Create - buat PO baru dengan status draft, id hasil insert dikembalikan
+func (repo *PurchaseOrderRepository) Create(req models.PurchaseOrderRequest) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	outletID, err := resolveOutlet(tx, req.OutletID)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = tx.QueryRow("INSERT INTO purchase_orders (supplier_id, outlet_id, status, notes) VALUES ($1, $2, $3, $4) RETURNING id",
+		req.SupplierID, outletID, models.PurchaseOrderStatusDraft, nullIfEmpty(req.Notes)).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := insertPurchaseOrderItems(tx, id, req.Items); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Update - ganti supplier, outlet tujuan, catatan dan seluruh item PO. Hanya boleh selama draft.
+func (repo *PurchaseOrderRepository) Update(id int, req models.PurchaseOrderRequest) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockPurchaseOrder(tx, id, models.PurchaseOrderStatusDraft); err != nil {
+		return err
+	}
+
+	outletID, err := resolveOutlet(tx, req.OutletID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE purchase_orders SET supplier_id = $1, outlet_id = $2, notes = $3 WHERE id = $4",
+		req.SupplierID, outletID, nullIfEmpty(req.Notes), id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM purchase_order_items WHERE purchase_order_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if err := insertPurchaseOrderItems(tx, id, req.Items); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertPurchaseOrderItems - simpan item PO beserta nama produk dan hitung ulang total_cost
+func insertPurchaseOrderItems(tx *sql.Tx, purchaseOrderID int, items []models.PurchaseOrderItemRequest) error {
+	totalCost := 0
+	for _, item := range items {
+		var name, unit string
+		var archived bool
+		err := tx.QueryRow("SELECT name, unit, archived_at IS NOT NULL FROM products WHERE id = $1", item.ProductID).Scan(&name, &unit, &archived)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: product id %d", models.ErrProductNotFound, item.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+		if archived {
+			return fmt.Errorf("%w: product id %d (%s)", models.ErrProductArchived, item.ProductID, name)
+		}
+		if unit == models.UnitPcs && item.Quantity != math.Trunc(item.Quantity) {
+			return fmt.Errorf("%w: product id %d is counted per pcs, quantity must be a whole number", models.ErrInvalidQuantity, item.ProductID)
+		}
+
+		subtotal := int(math.Round(item.Quantity * float64(item.UnitCost)))
+		totalCost += subtotal
+
+		_, err = tx.Exec(`INSERT INTO purchase_order_items (purchase_order_id, product_id, product_name, quantity, unit_cost, subtotal)
+			VALUES ($1, $2, $3, $4, $5, $6)`, purchaseOrderID, item.ProductID, name, item.Quantity, item.UnitCost, subtotal)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec("UPDATE purchase_orders SET total_cost = $1 WHERE id = $2", totalCost, purchaseOrderID)
+	return err
+}
+
+// List - daftar PO tanpa item, terbaru dulu
+func (repo *PurchaseOrderRepository) List(filter models.PurchaseOrderFilter) ([]models.PurchaseOrder, int, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Status != "" {
+		addCondition("po.status = $%d", filter.Status)
+	}
+	if filter.SupplierID != 0 {
+		addCondition("po.supplier_id = $%d", filter.SupplierID)
+	}
+	if filter.OutletID != 0 {
+		addCondition("po.outlet_id = $%d", filter.OutletID)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM purchase_orders po"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`SELECT po.id, po.supplier_id, po.outlet_id, s.name, po.status, COALESCE(po.notes, ''), po.total_cost, po.created_at, po.ordered_at, po.received_at
+		FROM purchase_orders po
+		JOIN suppliers s ON s.id = po.supplier_id%s
+		ORDER BY po.created_at DESC, po.id DESC LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	rows, err := repo.db.Query(query, append(args, filter.Limit, (filter.Page-1)*filter.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	orders := make([]models.PurchaseOrder, 0)
+	for rows.Next() {
+		var po models.PurchaseOrder
+		var supplierName string
+		if err := rows.Scan(&po.ID, &po.SupplierID, &po.OutletID, &supplierName, &po.Status, &po.Notes, &po.TotalCost, &po.CreatedAt, &po.OrderedAt, &po.ReceivedAt); err != nil {
+			return nil, 0, err
+		}
+		po.Supplier = &models.Supplier{ID: po.SupplierID, Name: supplierName}
+		orders = append(orders, po)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
+
+// GetByID - PO beserta supplier, item dan riwayat penerimaan barang
+func (repo *PurchaseOrderRepository) GetByID(id int) (*models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+	var supplier models.Supplier
+	err := repo.db.QueryRow(`
+		SELECT po.id, po.supplier_id, po.outlet_id, po.status, COALESCE(po.notes, ''), po.total_cost, po.created_at, po.ordered_at, po.received_at,
+			s.name, COALESCE(s.contact_name, ''), COALESCE(s.phone, ''), COALESCE(s.email, ''), COALESCE(s.address, ''), s.archived_at
+		FROM purchase_orders po
+		JOIN suppliers s ON s.id = po.supplier_id
+		WHERE po.id = $1`, id).
+		Scan(&po.ID, &po.SupplierID, &po.OutletID, &po.Status, &po.Notes, &po.TotalCost, &po.CreatedAt, &po.OrderedAt, &po.ReceivedAt,
+			&supplier.Name, &supplier.ContactName, &supplier.Phone, &supplier.Email, &supplier.Address, &supplier.ArchivedAt)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	supplier.ID = po.SupplierID
+	po.Supplier = &supplier
+
+	rows, err := repo.db.Query(`
+		SELECT id, purchase_order_id, product_id, product_name, quantity, received_quantity, unit_cost, subtotal
+		FROM purchase_order_items WHERE purchase_order_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	po.Items = make([]models.PurchaseOrderItem, 0)
+	for rows.Next() {
+		var item models.PurchaseOrderItem
+		if err := rows.Scan(&item.ID, &item.PurchaseOrderID, &item.ProductID, &item.ProductName, &item.Quantity, &item.ReceivedQuantity, &item.UnitCost, &item.Subtotal); err != nil {
+			return nil, err
+		}
+		po.Items = append(po.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	po.Receipts, err = repo.getReceipts(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &po, nil
+}
+
+// getReceipts - semua goods receipt sebuah PO beserta item-nya
+func (repo *PurchaseOrderRepository) getReceipts(purchaseOrderID int) ([]models.GoodsReceipt, error) {
+	rows, err := repo.db.Query(`
+		SELECT id, purchase_order_id, COALESCE(notes, ''), created_at
+		FROM goods_receipts WHERE purchase_order_id = $1 ORDER BY id`, purchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts := make([]models.GoodsReceipt, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var gr models.GoodsReceipt
+		if err := rows.Scan(&gr.ID, &gr.PurchaseOrderID, &gr.Notes, &gr.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		gr.Items = make([]models.GoodsReceiptItem, 0)
+		index[gr.ID] = len(receipts)
+		receipts = append(receipts, gr)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = repo.db.Query(`
+		SELECT gri.id, gri.goods_receipt_id, gri.purchase_order_item_id, gri.product_id, gri.quantity, gri.unit_cost,
+			COALESCE(gri.batch_number, ''), TO_CHAR(gri.expiry_date, 'YYYY-MM-DD')
+		FROM goods_receipt_items gri
+		JOIN goods_receipts gr ON gr.id = gri.goods_receipt_id
+		WHERE gr.purchase_order_id = $1
+		ORDER BY gri.id`, purchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.GoodsReceiptItem
+		if err := rows.Scan(&item.ID, &item.GoodsReceiptID, &item.PurchaseOrderItemID, &item.ProductID, &item.Quantity, &item.UnitCost,
+			&item.BatchNumber, &item.ExpiryDate); err != nil {
+			return nil, err
+		}
+		i := index[item.GoodsReceiptID]
+		receipts[i].Items = append(receipts[i].Items, item)
+	}
+
+	return receipts, rows.Err()
+}
+
+// MarkOrdered - draft -> ordered, PO tanpa item tidak bisa dipesan
+func (repo *PurchaseOrderRepository) MarkOrdered(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockPurchaseOrder(tx, id, models.PurchaseOrderStatusDraft); err != nil {
+		return err
+	}
+
+	var itemCount int
+	err = tx.QueryRow("SELECT COUNT(*) FROM purchase_order_items WHERE purchase_order_id = $1", id).Scan(&itemCount)
+	if err != nil {
+		return err
+	}
+	if itemCount == 0 {
+		return fmt.Errorf("%w: purchase order has no items", models.ErrInvalidPurchaseOrderStatus)
+	}
+
+	_, err = tx.Exec("UPDATE purchase_orders SET status = $1, ordered_at = NOW() WHERE id = $2", models.PurchaseOrderStatusOrdered, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Cancel - draft atau ordered -> cancelled. PO yang sudah menerima barang tidak bisa dibatalkan.
+func (repo *PurchaseOrderRepository) Cancel(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockPurchaseOrder(tx, id, models.PurchaseOrderStatusDraft, models.PurchaseOrderStatusOrdered); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE purchase_orders SET status = $1 WHERE id = $2", models.PurchaseOrderStatusCancelled, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Receive - catat penerimaan barang: tambah stok outlet PO lewat ledger (receipt), hitung ulang harga pokok
+// dengan moving average, lalu set status PO ke partially_received atau received.
+func (repo *PurchaseOrderRepository) Receive(id int, req models.GoodsReceiptRequest) (*models.GoodsReceipt, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockPurchaseOrder(tx, id, models.PurchaseOrderStatusOrdered, models.PurchaseOrderStatusPartiallyReceived); err != nil {
+		return nil, err
+	}
+
+	var outletID int
+	if err := tx.QueryRow("SELECT outlet_id FROM purchase_orders WHERE id = $1", id).Scan(&outletID); err != nil {
+		return nil, err
+	}
+
+	type orderLine struct {
+		productID int
+		unit      string
+		batched   bool
+		unitCost  int
+		remaining float64
+	}
+	rows, err := tx.Query(`
+		SELECT poi.id, poi.product_id, p.unit, p.batch_tracked, poi.unit_cost, poi.quantity - poi.received_quantity
+		FROM purchase_order_items poi
+		JOIN products p ON p.id = poi.product_id
+		WHERE poi.purchase_order_id = $1
+		ORDER BY poi.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	lines := make(map[int]*orderLine)
+	lineIDs := make([]int, 0)
+	for rows.Next() {
+		var lineID int
+		var line orderLine
+		if err := rows.Scan(&lineID, &line.productID, &line.unit, &line.batched, &line.unitCost, &line.remaining); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lines[lineID] = &line
+		lineIDs = append(lineIDs, lineID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items := req.Items
+	if len(items) == 0 {
+		for _, lineID := range lineIDs {
+			if lines[lineID].remaining > 0 {
+				items = append(items, models.GoodsReceiptItemRequest{PurchaseOrderItemID: lineID, Quantity: lines[lineID].remaining})
+			}
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: nothing left to receive", models.ErrOverReceipt)
+	}
+
+	receipt := &models.GoodsReceipt{
+		PurchaseOrderID: id,
+		Notes:           req.Notes,
+		Items:           make([]models.GoodsReceiptItem, 0, len(items)),
+	}
+	for _, item := range items {
+		line, ok := lines[item.PurchaseOrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: item id %d does not belong to purchase order %d", models.ErrOverReceipt, item.PurchaseOrderItemID, id)
+		}
+		if item.Quantity > line.remaining {
+			return nil, fmt.Errorf("%w: item id %d only has %g left to receive", models.ErrOverReceipt, item.PurchaseOrderItemID, line.remaining)
+		}
+		if line.unit == models.UnitPcs && item.Quantity != math.Trunc(item.Quantity) {
+			return nil, fmt.Errorf("%w: product id %d is counted per pcs, quantity must be a whole number", models.ErrInvalidQuantity, line.productID)
+		}
+		if line.batched && item.BatchNumber == "" {
+			return nil, fmt.Errorf("%w: product id %d", models.ErrBatchRequired, line.productID)
+		}
+		line.remaining = models.RoundQuantity(line.remaining - item.Quantity)
+
+		unitCost := line.unitCost
+		if item.UnitCost != nil {
+			unitCost = *item.UnitCost
+		}
+		receipt.Items = append(receipt.Items, models.GoodsReceiptItem{
+			PurchaseOrderItemID: item.PurchaseOrderItemID,
+			ProductID:           line.productID,
+			Quantity:            item.Quantity,
+			UnitCost:            unitCost,
+			BatchNumber:         item.BatchNumber,
+			ExpiryDate:          item.ExpiryDate,
+		})
+	}
+
+	err = tx.QueryRow("INSERT INTO goods_receipts (purchase_order_id, notes) VALUES ($1, $2) RETURNING id, created_at",
+		id, nullIfEmpty(receipt.Notes)).Scan(&receipt.ID, &receipt.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock produk dengan urutan id yang sama seperti checkout supaya tidak deadlock
+	order := make([]int, len(receipt.Items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return receipt.Items[order[a]].ProductID < receipt.Items[order[b]].ProductID
+	})
+
+	reason := fmt.Sprintf("purchase order #%d", id)
+	for _, i := range order {
+		item := &receipt.Items[i]
+		item.GoodsReceiptID = receipt.ID
+
+		var stock float64
+		var costPrice int
+		err := tx.QueryRow("SELECT stock, cost_price FROM products WHERE id = $1 FOR UPDATE", item.ProductID).Scan(&stock, &costPrice)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec("UPDATE products SET cost_price = $1 WHERE id = $2",
+			models.MovingAverageCost(stock, costPrice, item.Quantity, item.UnitCost), item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = recordStockMovement(tx, &models.StockMovement{
+			ProductID:   item.ProductID,
+			OutletID:    outletID,
+			Type:        models.StockMovementReceipt,
+			Quantity:    item.Quantity,
+			Reason:      reason,
+			ReferenceID: &receipt.ID,
+			BatchNumber: item.BatchNumber,
+			ExpiryDate:  item.ExpiryDate,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.QueryRow(`INSERT INTO goods_receipt_items (goods_receipt_id, purchase_order_item_id, product_id, quantity, unit_cost, batch_number, expiry_date)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			receipt.ID, item.PurchaseOrderItemID, item.ProductID, item.Quantity, item.UnitCost,
+			nullIfEmpty(item.BatchNumber), item.ExpiryDate).Scan(&item.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec("UPDATE purchase_order_items SET received_quantity = received_quantity + $1 WHERE id = $2",
+			item.Quantity, item.PurchaseOrderItemID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	status := models.PurchaseOrderStatusReceived
+	for _, line := range lines {
+		if line.remaining > 0 {
+			status = models.PurchaseOrderStatusPartiallyReceived
+			break
+		}
+	}
+	query := "UPDATE purchase_orders SET status = $1 WHERE id = $2"
+	if status == models.PurchaseOrderStatusReceived {
+		query = "UPDATE purchase_orders SET status = $1, received_at = NOW() WHERE id = $2"
+	}
+	if _, err := tx.Exec(query, status, id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// lockPurchaseOrder - lock row PO dan pastikan statusnya salah satu dari allowed
+func lockPurchaseOrder(tx *sql.Tx, id int, allowed ...string) error {
+	var status string
+	err := tx.QueryRow("SELECT status FROM purchase_orders WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return models.ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, s := range allowed {
+		if s == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: status is %s", models.ErrInvalidPurchaseOrderStatus, status)
+}
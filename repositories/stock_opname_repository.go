@@ -0,0 +1,313 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"kasir-api/models"
+)
+
+type StockOpnameRepository struct {
+	db *sql.DB
+}
+
+func NewStockOpnameRepository(db *sql.DB) *StockOpnameRepository {
+	return &StockOpnameRepository{db: db}
+}
+
+// Create - buka sesi baru dan snapshot stok sistem outlet + harga pokok semua produk aktif (atau satu kategori)
+func (repo *StockOpnameRepository) Create(req models.CreateStockOpnameRequest) (*models.StockOpname, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	outletID, err := resolveOutlet(tx, req.OutletID)
+	if err != nil {
+		return nil, err
+	}
+
+	opname := &models.StockOpname{
+		OutletID:   outletID,
+		Status:     models.StockOpnameStatusOpen,
+		Notes:      req.Notes,
+		CategoryID: req.CategoryID,
+	}
+	err = tx.QueryRow("INSERT INTO stock_opnames (outlet_id, status, notes, category_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		opname.OutletID, opname.Status, nullIfEmpty(opname.Notes), opname.CategoryID).Scan(&opname.ID, &opname.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO stock_opname_items (stock_opname_id, product_id, product_name, sku, unit, system_stock, unit_cost)
+		SELECT $1, p.id, p.name, p.sku, p.unit, COALESCE(os.stock, 0), p.cost_price
+		FROM products p
+		LEFT JOIN outlet_stocks os ON os.product_id = p.id AND os.outlet_id = $2
+		WHERE p.archived_at IS NULL`
+	args := []interface{}{opname.ID, outletID}
+	if req.CategoryID != nil {
+		query += " AND p.category_id = $3"
+		args = append(args, *req.CategoryID)
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return repo.GetByID(opname.ID)
+}
+
+// List - daftar sesi tanpa item, terbaru dulu
+func (repo *StockOpnameRepository) List(filter models.StockOpnameFilter) ([]models.StockOpname, int, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.OutletID != 0 {
+		args = append(args, filter.OutletID)
+		conditions = append(conditions, fmt.Sprintf("outlet_id = $%d", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM stock_opnames"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf("SELECT id, outlet_id, status, COALESCE(notes, ''), category_id, created_at, posted_at FROM stock_opnames%s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d",
+		where, len(args)+1, len(args)+2)
+	rows, err := repo.db.Query(query, append(args, filter.Limit, (filter.Page-1)*filter.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	opnames := make([]models.StockOpname, 0)
+	for rows.Next() {
+		var o models.StockOpname
+		if err := rows.Scan(&o.ID, &o.OutletID, &o.Status, &o.Notes, &o.CategoryID, &o.CreatedAt, &o.PostedAt); err != nil {
+			return nil, 0, err
+		}
+		opnames = append(opnames, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return opnames, total, nil
+}
+
+// GetByID - sesi beserta semua item, variance dan ringkasannya
+func (repo *StockOpnameRepository) GetByID(id int) (*models.StockOpname, error) {
+	var o models.StockOpname
+	err := repo.db.QueryRow("SELECT id, outlet_id, status, COALESCE(notes, ''), category_id, created_at, posted_at FROM stock_opnames WHERE id = $1", id).
+		Scan(&o.ID, &o.OutletID, &o.Status, &o.Notes, &o.CategoryID, &o.CreatedAt, &o.PostedAt)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrStockOpnameNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT product_id, product_name, COALESCE(sku, ''), unit, system_stock, counted_quantity, unit_cost
+		FROM stock_opname_items
+		WHERE stock_opname_id = $1
+		ORDER BY product_name, product_id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	o.Items = make([]models.StockOpnameItem, 0)
+	for rows.Next() {
+		var item models.StockOpnameItem
+		if err := rows.Scan(&item.ProductID, &item.ProductName, &item.SKU, &item.Unit, &item.SystemStock, &item.CountedQuantity, &item.UnitCost); err != nil {
+			return nil, err
+		}
+		o.Items = append(o.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	o.Summarize()
+	return &o, nil
+}
+
+// AddCounts - tambahkan satu batch hitungan. Hitungan dijumlahkan ke counted_quantity dan
+// setiap baris dicatat di stock_opname_counts supaya jelas siapa menghitung berapa.
+func (repo *StockOpnameRepository) AddCounts(id int, req models.StockOpnameCountRequest) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenStockOpname(tx, id); err != nil {
+		return err
+	}
+
+	for _, item := range req.Items {
+		var unit string
+		var counted float64
+		err := tx.QueryRow(`
+			UPDATE stock_opname_items SET counted_quantity = COALESCE(counted_quantity, 0) + $1
+			WHERE stock_opname_id = $2 AND product_id = $3
+			RETURNING unit, counted_quantity`, item.Quantity, id, item.ProductID).Scan(&unit, &counted)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: product id %d", models.ErrProductNotInOpname, item.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+
+		if unit == models.UnitPcs && item.Quantity != math.Trunc(item.Quantity) {
+			return fmt.Errorf("%w: product id %d is counted per pcs, quantity must be a whole number", models.ErrInvalidQuantity, item.ProductID)
+		}
+		if counted < 0 {
+			return fmt.Errorf("%w: counted quantity for product id %d would be %g", models.ErrInvalidQuantity, item.ProductID, counted)
+		}
+
+		_, err = tx.Exec("INSERT INTO stock_opname_counts (stock_opname_id, product_id, quantity, counted_by) VALUES ($1, $2, $3, $4)",
+			id, item.ProductID, item.Quantity, nullIfEmpty(req.CountedBy))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Post - terapkan selisih (counted - system_stock snapshot) ke stok produk di outlet sesi dalam satu tx.
+// Selisih ditambahkan ke stok saat ini, jadi penjualan selama penghitungan tidak tertimpa.
+// Produk yang tidak dihitung tidak diubah.
+func (repo *StockOpnameRepository) Post(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenStockOpname(tx, id); err != nil {
+		return err
+	}
+
+	var outletID int
+	if err := tx.QueryRow("SELECT outlet_id FROM stock_opnames WHERE id = $1", id).Scan(&outletID); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT product_id, counted_quantity - system_stock
+		FROM stock_opname_items
+		WHERE stock_opname_id = $1 AND counted_quantity IS NOT NULL AND counted_quantity <> system_stock`, id)
+	if err != nil {
+		return err
+	}
+	variances := make(map[int]float64)
+	productIDs := make([]int, 0)
+	for rows.Next() {
+		var productID int
+		var variance float64
+		if err := rows.Scan(&productID, &variance); err != nil {
+			rows.Close()
+			return err
+		}
+		variances[productID] = variance
+		productIDs = append(productIDs, productID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Lock produk dengan urutan id yang sama seperti checkout supaya tidak deadlock
+	sort.Ints(productIDs)
+	reason := fmt.Sprintf("stock opname #%d", id)
+	for _, productID := range productIDs {
+		err := tx.QueryRow("SELECT id FROM products WHERE id = $1 FOR UPDATE", productID).Scan(&productID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		stock, err := lockOutletStock(tx, productID, outletID)
+		if err != nil {
+			return err
+		}
+		if models.RoundQuantity(stock+variances[productID]) < 0 {
+			return fmt.Errorf("%w: product id %d has %g in stock, variance is %g", models.ErrNegativeStock, productID, stock, variances[productID])
+		}
+
+		err = recordStockMovement(tx, &models.StockMovement{
+			ProductID:   productID,
+			OutletID:    outletID,
+			Type:        models.StockMovementOpname,
+			Quantity:    variances[productID],
+			Reason:      reason,
+			ReferenceID: &id,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE stock_opnames SET status = $1, posted_at = NOW() WHERE id = $2", models.StockOpnameStatusPosted, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Cancel - batalkan sesi yang masih open, stok tidak berubah
+func (repo *StockOpnameRepository) Cancel(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenStockOpname(tx, id); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE stock_opnames SET status = $1 WHERE id = $2", models.StockOpnameStatusCancelled, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockOpenStockOpname - lock row sesi dan pastikan statusnya masih open
+func lockOpenStockOpname(tx *sql.Tx, id int) error {
+	var status string
+	err := tx.QueryRow("SELECT status FROM stock_opnames WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return models.ErrStockOpnameNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.StockOpnameStatusOpen {
+		return fmt.Errorf("%w: status is %s", models.ErrStockOpnameNotOpen, status)
+	}
+	return nil
+}
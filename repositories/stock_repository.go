@@ -0,0 +1,442 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"kasir-api/models"
+)
+
+type StockRepository struct {
+	db *sql.DB
+}
+
+func NewStockRepository(db *sql.DB) *StockRepository {
+	return &StockRepository{db: db}
+}
+
+// Adjust - catat penyesuaian stok manual (receipt, adjustment, damage) untuk satu produk
+func (repo *StockRepository) Adjust(productID int, req models.StockAdjustmentRequest) (*models.StockMovement, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	outletID, err := resolveOutlet(tx, req.OutletID)
+	if err != nil {
+		return nil, err
+	}
+
+	var unit string
+	var stock float64
+	var archived, batchTracked bool
+	err = tx.QueryRow(`SELECT unit, archived_at IS NOT NULL, batch_tracked
+		FROM products
+		WHERE id = $1
+		FOR UPDATE`, productID).
+		Scan(&unit, &archived, &batchTracked)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	stock, err = lockOutletStock(tx, productID, outletID)
+	if err != nil {
+		return nil, err
+	}
+	if archived {
+		return nil, models.ErrProductArchived
+	}
+
+	quantity := models.RoundQuantity(req.Quantity)
+	if unit == models.UnitPcs && quantity != math.Trunc(quantity) {
+		return nil, fmt.Errorf("%w: product is counted per pcs, quantity must be a whole number", models.ErrInvalidQuantity)
+	}
+	if models.RoundQuantity(stock+quantity) < 0 {
+		return nil, fmt.Errorf("%w: current stock at outlet %d is %g", models.ErrNegativeStock, outletID, stock)
+	}
+	if batchTracked && quantity > 0 && req.BatchNumber == "" {
+		return nil, models.ErrBatchRequired
+	}
+
+	movement := &models.StockMovement{
+		ProductID:   productID,
+		OutletID:    outletID,
+		Type:        req.Type,
+		Quantity:    quantity,
+		Reason:      req.Reason,
+		ReferenceID: req.ReferenceID,
+	}
+	if batchTracked {
+		movement.BatchNumber = req.BatchNumber
+		movement.ExpiryDate = req.ExpiryDate
+	}
+	if err := recordStockMovement(tx, movement); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+// GetMovements - riwayat pergerakan stok satu produk, terbaru dulu
+func (repo *StockRepository) GetMovements(filter models.StockMovementFilter) ([]models.StockMovement, int, error) {
+	var exists bool
+	err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)", filter.ProductID).Scan(&exists)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !exists {
+		return nil, 0, models.ErrProductNotFound
+	}
+
+	conditions := []string{"product_id = $1"}
+	args := []interface{}{filter.ProductID}
+	if filter.OutletID != 0 {
+		args = append(args, filter.OutletID)
+		conditions = append(conditions, fmt.Sprintf("outlet_id = $%d", len(args)))
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		conditions = append(conditions, fmt.Sprintf("type = $%d", len(args)))
+	}
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	err = repo.db.QueryRow("SELECT COUNT(*) FROM stock_movements"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`SELECT id, product_id, outlet_id, type, quantity, stock_before, stock_after, COALESCE(reason, ''), reference_id, created_at
+		FROM stock_movements%s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	rows, err := repo.db.Query(query, append(args, filter.Limit, (filter.Page-1)*filter.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	movements := make([]models.StockMovement, 0)
+	index := make(map[int]int)
+	ids := make([]int, 0)
+	for rows.Next() {
+		var m models.StockMovement
+		if err := rows.Scan(&m.ID, &m.ProductID, &m.OutletID, &m.Type, &m.Quantity, &m.StockBefore, &m.StockAfter, &m.Reason, &m.ReferenceID, &m.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		index[m.ID] = len(movements)
+		ids = append(ids, m.ID)
+		movements = append(movements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return movements, total, nil
+	}
+
+	placeholders, args := inPlaceholders(ids)
+	batchRows, err := repo.db.Query(`
+		SELECT smb.stock_movement_id, b.id, b.batch_number, TO_CHAR(b.expiry_date, 'YYYY-MM-DD'), smb.quantity
+		FROM stock_movement_batches smb
+		JOIN product_batches b ON b.id = smb.batch_id
+		WHERE smb.stock_movement_id IN (`+placeholders+`)
+		ORDER BY smb.id`, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer batchRows.Close()
+
+	for batchRows.Next() {
+		var movementID int
+		var b models.BatchAllocation
+		if err := batchRows.Scan(&movementID, &b.BatchID, &b.BatchNumber, &b.ExpiryDate, &b.Quantity); err != nil {
+			return nil, 0, err
+		}
+		m := &movements[index[movementID]]
+		m.Batches = append(m.Batches, b)
+	}
+
+	return movements, total, batchRows.Err()
+}
+
+// GetBatches - batch sebuah produk dengan urutan FEFO, batch kosong hanya kalau includeEmpty.
+// outletID 0 berarti semua outlet.
+func (repo *StockRepository) GetBatches(productID, outletID int, includeEmpty bool) ([]models.ProductBatch, error) {
+	var exists bool
+	err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, models.ErrProductNotFound
+	}
+
+	query := `SELECT id, product_id, outlet_id, batch_number, TO_CHAR(expiry_date, 'YYYY-MM-DD'), quantity, created_at
+		FROM product_batches WHERE product_id = $1`
+	args := []interface{}{productID}
+	if outletID != 0 {
+		args = append(args, outletID)
+		query += " AND outlet_id = $2"
+	}
+	if !includeEmpty {
+		query += " AND quantity > 0"
+	}
+	rows, err := repo.db.Query(query+" ORDER BY expiry_date ASC NULLS LAST, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := make([]models.ProductBatch, 0)
+	for rows.Next() {
+		var b models.ProductBatch
+		if err := rows.Scan(&b.ID, &b.ProductID, &b.OutletID, &b.BatchNumber, &b.ExpiryDate, &b.Quantity, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+
+	return batches, rows.Err()
+}
+
+// GetExpiringBatches - batch berisi yang kadaluarsa dalam days hari ke depan, termasuk yang sudah lewat.
+// outletID 0 berarti semua outlet.
+func (repo *StockRepository) GetExpiringBatches(days, outletID int) ([]models.ExpiringBatch, error) {
+	query := `
+		SELECT b.id, b.product_id, b.outlet_id, b.batch_number, TO_CHAR(b.expiry_date, 'YYYY-MM-DD'), b.quantity, b.created_at,
+			p.name, COALESCE(p.sku, ''), o.name, b.expiry_date - CURRENT_DATE, p.cost_price
+		FROM product_batches b
+		JOIN products p ON p.id = b.product_id
+		JOIN outlets o ON o.id = b.outlet_id
+		WHERE b.quantity > 0 AND p.batch_tracked AND p.archived_at IS NULL
+			AND b.expiry_date <= CURRENT_DATE + $1::int`
+	args := []interface{}{days}
+	if outletID != 0 {
+		args = append(args, outletID)
+		query += " AND b.outlet_id = $2"
+	}
+	rows, err := repo.db.Query(query+" ORDER BY b.expiry_date, p.name, b.outlet_id, b.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.ExpiringBatch, 0)
+	for rows.Next() {
+		var item models.ExpiringBatch
+		var costPrice int
+		err := rows.Scan(&item.ID, &item.ProductID, &item.OutletID, &item.BatchNumber, &item.ExpiryDate, &item.Quantity, &item.CreatedAt,
+			&item.ProductName, &item.SKU, &item.OutletName, &item.DaysLeft, &costPrice)
+		if err != nil {
+			return nil, err
+		}
+		item.Value = int(math.Round(item.Quantity * float64(costPrice)))
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// lockOutletStock - lock row outlet_stocks produk di outlet dan kembalikan stoknya.
+// Produk yang belum punya row di outlet itu dianggap stok 0.
+func lockOutletStock(tx *sql.Tx, productID, outletID int) (float64, error) {
+	var stock float64
+	err := tx.QueryRow("SELECT stock FROM outlet_stocks WHERE product_id = $1 AND outlet_id = $2 FOR UPDATE",
+		productID, outletID).Scan(&stock)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return stock, err
+}
+
+// recordStockMovement - ubah stok produk sebesar movement.Quantity dan tulis ledger-nya di tx yang sama.
+// Row produk sebaiknya sudah di-lock oleh pemanggil dan OutletID sudah di-resolve.
+// Stok outlet (outlet_stocks) dan total semua outlet (products.stock) diubah bersama.
+// StockBefore/StockAfter, ID dan CreatedAt diisi di sini.
+// Untuk produk batch-tracked, batch di outlet itu ikut diubah supaya jumlahnya tetap sama dengan stok outlet.
+func recordStockMovement(tx *sql.Tx, movement *models.StockMovement) error {
+	var batchTracked bool
+	err := tx.QueryRow("UPDATE products SET stock = stock + $1 WHERE id = $2 RETURNING batch_tracked",
+		movement.Quantity, movement.ProductID).Scan(&batchTracked)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: product id %d", models.ErrProductNotFound, movement.ProductID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Stok outlet tidak boleh minus: upsert tidak mengubah apa-apa kalau hasilnya di bawah nol
+	err = tx.QueryRow(`INSERT INTO outlet_stocks (outlet_id, product_id, stock) VALUES ($1, $2, $3)
+		ON CONFLICT (outlet_id, product_id) DO UPDATE SET stock = outlet_stocks.stock + EXCLUDED.stock
+		WHERE outlet_stocks.stock + EXCLUDED.stock >= 0
+		RETURNING stock - $3, stock`,
+		movement.OutletID, movement.ProductID, movement.Quantity).Scan(&movement.StockBefore, &movement.StockAfter)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: product id %d at outlet %d", models.ErrNegativeStock, movement.ProductID, movement.OutletID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if batchTracked {
+		if err := applyBatchMovement(tx, movement); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRow(`INSERT INTO stock_movements (product_id, outlet_id, type, quantity, stock_before, stock_after, reason, reference_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`,
+		movement.ProductID, movement.OutletID, movement.Type, movement.Quantity, movement.StockBefore, movement.StockAfter,
+		nullIfEmpty(movement.Reason), movement.ReferenceID).Scan(&movement.ID, &movement.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range movement.Batches {
+		_, err := tx.Exec("INSERT INTO stock_movement_batches (stock_movement_id, batch_id, quantity) VALUES ($1, $2, $3)",
+			movement.ID, b.BatchID, b.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyBatchMovement - tentukan batch yang terkena movement lalu ubah quantity-nya.
+// Semua batch yang dipakai ada di outlet movement.
+// Stok masuk: batch BatchNumber (dibuat kalau belum ada), tanpa nomor masuk ke batch dengan kadaluarsa
+// paling akhir atau DefaultBatchNumber. Stok keluar: batch BatchNumber, tanpa nomor diambil FEFO.
+func applyBatchMovement(tx *sql.Tx, movement *models.StockMovement) error {
+	if len(movement.Batches) == 0 {
+		if movement.Quantity > 0 {
+			batchNumber := movement.BatchNumber
+			if batchNumber == "" {
+				err := tx.QueryRow(`SELECT batch_number FROM product_batches WHERE product_id = $1 AND outlet_id = $2
+					ORDER BY expiry_date DESC NULLS FIRST, id DESC LIMIT 1`, movement.ProductID, movement.OutletID).Scan(&batchNumber)
+				if err == sql.ErrNoRows {
+					batchNumber = models.DefaultBatchNumber
+				} else if err != nil {
+					return err
+				}
+			}
+
+			var b models.BatchAllocation
+			err := tx.QueryRow(`INSERT INTO product_batches (product_id, outlet_id, batch_number, expiry_date, quantity) VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (product_id, outlet_id, batch_number) DO UPDATE
+				SET quantity = product_batches.quantity + EXCLUDED.quantity,
+					expiry_date = COALESCE(product_batches.expiry_date, EXCLUDED.expiry_date)
+				RETURNING id, batch_number, TO_CHAR(expiry_date, 'YYYY-MM-DD')`,
+				movement.ProductID, movement.OutletID, batchNumber, movement.ExpiryDate, movement.Quantity).Scan(&b.BatchID, &b.BatchNumber, &b.ExpiryDate)
+			if err != nil {
+				return err
+			}
+			b.Quantity = movement.Quantity
+			movement.Batches = []models.BatchAllocation{b}
+			return nil
+		}
+
+		available, err := lockProductBatches(tx, movement.OutletID, movement.ProductID, movement.BatchNumber, false)
+		if err != nil {
+			return err
+		}
+		if movement.BatchNumber != "" && len(available) == 0 {
+			return fmt.Errorf("%w: %s", models.ErrBatchNotFound, movement.BatchNumber)
+		}
+
+		allocations, _, shortfall := models.AllocateFEFO(available, -movement.Quantity)
+		if shortfall > 0 {
+			return fmt.Errorf("%w: batches of product id %d at outlet %d are short by %g", models.ErrNegativeStock, movement.ProductID, movement.OutletID, shortfall)
+		}
+		for i := range allocations {
+			allocations[i].Quantity = -allocations[i].Quantity
+		}
+		movement.Batches = allocations
+	}
+
+	for _, b := range movement.Batches {
+		var quantity float64
+		err := tx.QueryRow("UPDATE product_batches SET quantity = quantity + $1 WHERE id = $2 AND product_id = $3 AND outlet_id = $4 RETURNING quantity",
+			b.Quantity, b.BatchID, movement.ProductID, movement.OutletID).Scan(&quantity)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: batch id %d", models.ErrBatchNotFound, b.BatchID)
+		}
+		if err != nil {
+			return err
+		}
+		if models.RoundQuantity(quantity) < 0 {
+			return fmt.Errorf("%w: batch %s", models.ErrNegativeStock, b.BatchNumber)
+		}
+	}
+
+	return nil
+}
+
+// lockProductBatches - lock batch di satu outlet yang masih berisi dengan urutan FEFO (kadaluarsa terdekat dulu,
+// tanpa kadaluarsa paling akhir). batchNumber membatasi ke satu batch, sellableOnly melewati batch kadaluarsa.
+func lockProductBatches(tx *sql.Tx, outletID, productID int, batchNumber string, sellableOnly bool) ([]models.BatchAllocation, error) {
+	conditions := []string{"product_id = $1", "outlet_id = $2", "quantity > 0"}
+	args := []interface{}{productID, outletID}
+	if batchNumber != "" {
+		args = append(args, batchNumber)
+		conditions = append(conditions, fmt.Sprintf("batch_number = $%d", len(args)))
+	}
+	if sellableOnly {
+		conditions = append(conditions, "(expiry_date IS NULL OR expiry_date >= CURRENT_DATE)")
+	}
+
+	rows, err := tx.Query(`SELECT id, batch_number, TO_CHAR(expiry_date, 'YYYY-MM-DD'), quantity FROM product_batches
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY expiry_date ASC NULLS LAST, id
+		FOR UPDATE`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := make([]models.BatchAllocation, 0)
+	for rows.Next() {
+		var b models.BatchAllocation
+		if err := rows.Scan(&b.BatchID, &b.BatchNumber, &b.ExpiryDate, &b.Quantity); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+
+	return batches, rows.Err()
+}
+
+// ensureProductBatch - pastikan batch batchNumber ada di outlet (quantity 0 kalau baru) tanpa mengubah stoknya.
+// Kadaluarsa batch yang sudah ada tidak ditimpa. Mengembalikan id dan tanggal kadaluarsa batch.
+func ensureProductBatch(tx *sql.Tx, productID, outletID int, batchNumber string, expiryDate *string) (int, *string, error) {
+	var id int
+	err := tx.QueryRow(`INSERT INTO product_batches (product_id, outlet_id, batch_number, expiry_date, quantity) VALUES ($1, $2, $3, $4, 0)
+		ON CONFLICT (product_id, outlet_id, batch_number) DO UPDATE
+		SET expiry_date = COALESCE(product_batches.expiry_date, EXCLUDED.expiry_date)
+		RETURNING id, TO_CHAR(expiry_date, 'YYYY-MM-DD')`,
+		productID, outletID, batchNumber, expiryDate).Scan(&id, &expiryDate)
+	return id, expiryDate, err
+}
+
+// resetProductBatches - mulai pencatatan batch dari stok saat ini: batch lama dikosongkan dan
+// stok tiap outlet masuk ke DefaultBatchNumber di outlet itu. Dipakai saat produk baru diberi flag batch_tracked.
+func resetProductBatches(tx *sql.Tx, productID int) error {
+	_, err := tx.Exec("UPDATE product_batches SET quantity = 0 WHERE product_id = $1 AND quantity <> 0", productID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO product_batches (product_id, outlet_id, batch_number, quantity)
+		SELECT product_id, outlet_id, $2, stock FROM outlet_stocks WHERE product_id = $1 AND stock > 0
+		ON CONFLICT (product_id, outlet_id, batch_number) DO UPDATE SET quantity = EXCLUDED.quantity`,
+		productID, models.DefaultBatchNumber)
+	return err
+}
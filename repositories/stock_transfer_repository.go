@@ -0,0 +1,477 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"kasir-api/models"
+)
+
+type StockTransferRepository struct {
+	db *sql.DB
+}
+
+func NewStockTransferRepository(db *sql.DB) *StockTransferRepository {
+	return &StockTransferRepository{db: db}
+}
+
+// Create - buat transfer baru dengan status draft, stok belum berubah sampai dispatch
+func (repo *StockTransferRepository) Create(req models.StockTransferRequest) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := resolveOutlet(tx, req.SourceOutletID); err != nil {
+		return 0, err
+	}
+	if _, err := resolveOutlet(tx, req.DestinationOutletID); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = tx.QueryRow("INSERT INTO stock_transfers (source_outlet_id, destination_outlet_id, status, notes) VALUES ($1, $2, $3, $4) RETURNING id",
+		req.SourceOutletID, req.DestinationOutletID, models.StockTransferStatusDraft, nullIfEmpty(req.Notes)).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range req.Items {
+		var name, unit string
+		var archived bool
+		err := tx.QueryRow("SELECT name, unit, archived_at IS NOT NULL FROM products WHERE id = $1", item.ProductID).Scan(&name, &unit, &archived)
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("%w: product id %d", models.ErrProductNotFound, item.ProductID)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if archived {
+			return 0, fmt.Errorf("%w: product id %d (%s)", models.ErrProductArchived, item.ProductID, name)
+		}
+		if unit == models.UnitPcs && item.Quantity != math.Trunc(item.Quantity) {
+			return 0, fmt.Errorf("%w: product id %d is counted per pcs, quantity must be a whole number", models.ErrInvalidQuantity, item.ProductID)
+		}
+
+		_, err = tx.Exec("INSERT INTO stock_transfer_items (stock_transfer_id, product_id, product_name, quantity) VALUES ($1, $2, $3, $4)",
+			id, item.ProductID, name, item.Quantity)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// List - daftar transfer tanpa item, terbaru dulu
+func (repo *StockTransferRepository) List(filter models.StockTransferFilter) ([]models.StockTransfer, int, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("st.status = $%d", len(args)))
+	}
+	if filter.OutletID != 0 {
+		args = append(args, filter.OutletID)
+		conditions = append(conditions, fmt.Sprintf("(st.source_outlet_id = $%d OR st.destination_outlet_id = $%d)", len(args), len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM stock_transfers st"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`SELECT st.id, st.source_outlet_id, src.name, st.destination_outlet_id, dst.name, st.status,
+			COALESCE(st.notes, ''), COALESCE(st.receive_notes, ''), st.created_at, st.dispatched_at, st.received_at
+		FROM stock_transfers st
+		JOIN outlets src ON src.id = st.source_outlet_id
+		JOIN outlets dst ON dst.id = st.destination_outlet_id%s
+		ORDER BY st.created_at DESC, st.id DESC LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	rows, err := repo.db.Query(query, append(args, filter.Limit, (filter.Page-1)*filter.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	transfers := make([]models.StockTransfer, 0)
+	for rows.Next() {
+		var t models.StockTransfer
+		err := rows.Scan(&t.ID, &t.SourceOutletID, &t.SourceOutletName, &t.DestinationOutletID, &t.DestinationOutletName, &t.Status,
+			&t.Notes, &t.ReceiveNotes, &t.CreatedAt, &t.DispatchedAt, &t.ReceivedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		transfers = append(transfers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return transfers, total, nil
+}
+
+// GetByID - transfer beserta item, selisih penerimaan dan batch yang dikirim
+func (repo *StockTransferRepository) GetByID(id int) (*models.StockTransfer, error) {
+	var t models.StockTransfer
+	err := repo.db.QueryRow(`
+		SELECT st.id, st.source_outlet_id, src.name, st.destination_outlet_id, dst.name, st.status,
+			COALESCE(st.notes, ''), COALESCE(st.receive_notes, ''), st.created_at, st.dispatched_at, st.received_at
+		FROM stock_transfers st
+		JOIN outlets src ON src.id = st.source_outlet_id
+		JOIN outlets dst ON dst.id = st.destination_outlet_id
+		WHERE st.id = $1`, id).
+		Scan(&t.ID, &t.SourceOutletID, &t.SourceOutletName, &t.DestinationOutletID, &t.DestinationOutletName, &t.Status,
+			&t.Notes, &t.ReceiveNotes, &t.CreatedAt, &t.DispatchedAt, &t.ReceivedAt)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrStockTransferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT id, stock_transfer_id, product_id, product_name, quantity, received_quantity, COALESCE(discrepancy_note, '')
+		FROM stock_transfer_items WHERE stock_transfer_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	t.Items = make([]models.StockTransferItem, 0)
+	for rows.Next() {
+		var item models.StockTransferItem
+		err := rows.Scan(&item.ID, &item.StockTransferID, &item.ProductID, &item.ProductName, &item.Quantity, &item.ReceivedQuantity, &item.DiscrepancyNote)
+		if err != nil {
+			return nil, err
+		}
+		if item.ReceivedQuantity != nil {
+			discrepancy := models.RoundQuantity(*item.ReceivedQuantity - item.Quantity)
+			item.Discrepancy = &discrepancy
+		}
+		t.Items = append(t.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	batches, err := repo.getItemBatches(id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range t.Items {
+		t.Items[i].Batches = batches[t.Items[i].ID]
+	}
+
+	return &t, nil
+}
+
+// getItemBatches - batch outlet asal yang dikirim, dikelompokkan per item id dengan urutan FEFO
+func (repo *StockTransferRepository) getItemBatches(stockTransferID int) (map[int][]models.BatchAllocation, error) {
+	rows, err := repo.db.Query(`
+		SELECT stb.stock_transfer_item_id, b.id, b.batch_number, TO_CHAR(b.expiry_date, 'YYYY-MM-DD'), stb.quantity
+		FROM stock_transfer_item_batches stb
+		JOIN stock_transfer_items sti ON sti.id = stb.stock_transfer_item_id
+		JOIN product_batches b ON b.id = stb.batch_id
+		WHERE sti.stock_transfer_id = $1
+		ORDER BY b.expiry_date ASC NULLS LAST, stb.id`, stockTransferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int][]models.BatchAllocation)
+	for rows.Next() {
+		var itemID int
+		var b models.BatchAllocation
+		if err := rows.Scan(&itemID, &b.BatchID, &b.BatchNumber, &b.ExpiryDate, &b.Quantity); err != nil {
+			return nil, err
+		}
+		result[itemID] = append(result[itemID], b)
+	}
+
+	return result, rows.Err()
+}
+
+// Dispatch - draft -> in_transit: kurangi stok outlet asal lewat ledger (transfer_out) dalam satu tx.
+// Produk batch-tracked diambil FEFO dan batch-nya dicatat supaya bisa dipindahkan utuh ke outlet tujuan.
+func (repo *StockTransferRepository) Dispatch(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sourceID, destinationID, err := lockStockTransfer(tx, id, models.StockTransferStatusDraft)
+	if err != nil {
+		return err
+	}
+	if _, err := resolveOutlet(tx, sourceID); err != nil {
+		return err
+	}
+	if _, err := resolveOutlet(tx, destinationID); err != nil {
+		return err
+	}
+
+	items, err := lockStockTransferItems(tx, id)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("%w: stock transfer has no items", models.ErrInvalidStockTransferStatus)
+	}
+
+	reason := fmt.Sprintf("stock transfer #%d to outlet %d", id, destinationID)
+	for _, item := range items {
+		var productID int
+		err := tx.QueryRow("SELECT id FROM products WHERE id = $1 FOR UPDATE", item.ProductID).Scan(&productID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: product id %d", models.ErrProductNotFound, item.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+		stock, err := lockOutletStock(tx, item.ProductID, sourceID)
+		if err != nil {
+			return err
+		}
+		if models.RoundQuantity(stock-item.Quantity) < 0 {
+			return fmt.Errorf("%w: product id %d has %g in stock at outlet %d, transfer needs %g",
+				models.ErrNegativeStock, item.ProductID, stock, sourceID, item.Quantity)
+		}
+
+		movement := &models.StockMovement{
+			ProductID:   item.ProductID,
+			OutletID:    sourceID,
+			Type:        models.StockMovementTransferOut,
+			Quantity:    -item.Quantity,
+			Reason:      reason,
+			ReferenceID: &id,
+		}
+		if err := recordStockMovement(tx, movement); err != nil {
+			return err
+		}
+
+		for _, b := range movement.Batches {
+			_, err := tx.Exec("INSERT INTO stock_transfer_item_batches (stock_transfer_item_id, batch_id, quantity) VALUES ($1, $2, $3)",
+				item.ID, b.BatchID, -b.Quantity)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = tx.Exec("UPDATE stock_transfers SET status = $1, dispatched_at = NOW() WHERE id = $2", models.StockTransferStatusInTransit, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Receive - in_transit -> received: semua quantity yang dikirim masuk ke outlet tujuan (transfer_in),
+// lalu kekurangannya langsung dicatat keluar sebagai damage supaya ledger transfer seimbang dan
+// selisihnya terlihat. Selisih per item juga disimpan; barang yang kurang tidak kembali ke outlet asal.
+// Untuk produk batch-tracked, batch yang diterima mengikuti batch yang dikirim dengan urutan FEFO,
+// sisanya dianggap batch yang hilang.
+func (repo *StockTransferRepository) Receive(id int, req models.StockTransferReceiveRequest) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sourceID, destinationID, err := lockStockTransfer(tx, id, models.StockTransferStatusInTransit)
+	if err != nil {
+		return err
+	}
+	if _, err := resolveOutlet(tx, destinationID); err != nil {
+		return err
+	}
+
+	items, err := lockStockTransferItems(tx, id)
+	if err != nil {
+		return err
+	}
+	received := make(map[int]models.StockTransferReceiveItem)
+	for _, item := range items {
+		received[item.ID] = models.StockTransferReceiveItem{StockTransferItemID: item.ID, ReceivedQuantity: item.Quantity}
+	}
+	for _, r := range req.Items {
+		if _, ok := received[r.StockTransferItemID]; !ok {
+			return fmt.Errorf("%w: item id %d does not belong to stock transfer %d", models.ErrInvalidQuantity, r.StockTransferItemID, id)
+		}
+		received[r.StockTransferItemID] = r
+	}
+
+	// Batch yang dikirim hanya ditulis saat dispatch, jadi aman dibaca di luar tx selama transfer in_transit
+	batches, err := repo.getItemBatches(id)
+	if err != nil {
+		return err
+	}
+
+	reason := fmt.Sprintf("stock transfer #%d from outlet %d", id, sourceID)
+	for _, item := range items {
+		r := received[item.ID]
+		if r.ReceivedQuantity > item.Quantity {
+			return fmt.Errorf("%w: item id %d only had %g dispatched", models.ErrInvalidQuantity, item.ID, item.Quantity)
+		}
+
+		var unit string
+		var batchTracked bool
+		err := tx.QueryRow("SELECT unit, batch_tracked FROM products WHERE id = $1 FOR UPDATE", item.ProductID).Scan(&unit, &batchTracked)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: product id %d", models.ErrProductNotFound, item.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+		if unit == models.UnitPcs && r.ReceivedQuantity != math.Trunc(r.ReceivedQuantity) {
+			return fmt.Errorf("%w: product id %d is counted per pcs, quantity must be a whole number", models.ErrInvalidQuantity, item.ProductID)
+		}
+
+		_, err = tx.Exec("UPDATE stock_transfer_items SET received_quantity = $1, discrepancy_note = $2 WHERE id = $3",
+			r.ReceivedQuantity, nullIfEmpty(r.Note), item.ID)
+		if err != nil {
+			return err
+		}
+
+		movement := &models.StockMovement{
+			ProductID:   item.ProductID,
+			OutletID:    destinationID,
+			Type:        models.StockMovementTransferIn,
+			Quantity:    item.Quantity,
+			Reason:      reason,
+			ReferenceID: &id,
+		}
+		var missingBatches []models.BatchAllocation
+		if batchTracked && len(batches[item.ID]) > 0 {
+			allocations, _, shortfall := models.AllocateFEFO(batches[item.ID], item.Quantity)
+			if shortfall > 0 {
+				// Batch yang dikirim tidak mencakup semuanya (misalnya flag batch_tracked baru dinyalakan)
+				allocations[len(allocations)-1].Quantity = models.RoundQuantity(allocations[len(allocations)-1].Quantity + shortfall)
+			}
+			for i, a := range allocations {
+				allocations[i].BatchID, allocations[i].ExpiryDate, err = ensureProductBatch(tx, item.ProductID, destinationID, a.BatchNumber, a.ExpiryDate)
+				if err != nil {
+					return err
+				}
+			}
+			movement.Batches = allocations
+
+			_, missingBatches, _ = models.AllocateFEFO(allocations, r.ReceivedQuantity)
+			for i := range missingBatches {
+				missingBatches[i].Quantity = -missingBatches[i].Quantity
+			}
+		}
+		if err := recordStockMovement(tx, movement); err != nil {
+			return err
+		}
+
+		missing := models.RoundQuantity(item.Quantity - r.ReceivedQuantity)
+		if missing == 0 {
+			continue
+		}
+		lossReason := fmt.Sprintf("stock transfer #%d from outlet %d: %g missing on receipt", id, sourceID, missing)
+		if r.Note != "" {
+			lossReason += " (" + r.Note + ")"
+		}
+		loss := &models.StockMovement{
+			ProductID:   item.ProductID,
+			OutletID:    destinationID,
+			Type:        models.StockMovementDamage,
+			Quantity:    -missing,
+			Reason:      lossReason,
+			ReferenceID: &id,
+			Batches:     missingBatches,
+		}
+		if err := recordStockMovement(tx, loss); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE stock_transfers SET status = $1, receive_notes = $2, received_at = NOW() WHERE id = $3",
+		models.StockTransferStatusReceived, nullIfEmpty(req.Notes), id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Cancel - draft -> cancelled. Transfer yang sudah dikirim harus diterima dulu.
+func (repo *StockTransferRepository) Cancel(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockStockTransfer(tx, id, models.StockTransferStatusDraft); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE stock_transfers SET status = $1 WHERE id = $2", models.StockTransferStatusCancelled, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockStockTransfer - lock row transfer, pastikan statusnya salah satu dari allowed dan kembalikan outlet asal/tujuan
+func lockStockTransfer(tx *sql.Tx, id int, allowed ...string) (int, int, error) {
+	var status string
+	var sourceID, destinationID int
+	err := tx.QueryRow("SELECT status, source_outlet_id, destination_outlet_id FROM stock_transfers WHERE id = $1 FOR UPDATE", id).
+		Scan(&status, &sourceID, &destinationID)
+	if err == sql.ErrNoRows {
+		return 0, 0, models.ErrStockTransferNotFound
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, s := range allowed {
+		if s == status {
+			return sourceID, destinationID, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("%w: status is %s", models.ErrInvalidStockTransferStatus, status)
+}
+
+// lockStockTransferItems - item transfer diurutkan per product id, urutan yang sama seperti checkout
+// supaya lock produk tidak deadlock
+func lockStockTransferItems(tx *sql.Tx, stockTransferID int) ([]models.StockTransferItem, error) {
+	rows, err := tx.Query("SELECT id, product_id, quantity FROM stock_transfer_items WHERE stock_transfer_id = $1 FOR UPDATE", stockTransferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.StockTransferItem, 0)
+	for rows.Next() {
+		var item models.StockTransferItem
+		if err := rows.Scan(&item.ID, &item.ProductID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(a, b int) bool {
+		return items[a].ProductID < items[b].ProductID
+	})
+	return items, nil
+}
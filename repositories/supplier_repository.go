@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/models"
+)
+
+type SupplierRepository struct {
+	db *sql.DB
+}
+
+func NewSupplierRepository(db *sql.DB) *SupplierRepository {
+	return &SupplierRepository{db: db}
+}
+
+// GetAll - supplier yang diarsipkan hanya ikut kalau includeArchived true
+func (repo *SupplierRepository) GetAll(includeArchived bool) ([]models.Supplier, error) {
+	query := "SELECT id, name, COALESCE(contact_name, ''), COALESCE(phone, ''), COALESCE(email, ''), COALESCE(address, ''), archived_at FROM suppliers"
+	if !includeArchived {
+		query += " WHERE archived_at IS NULL"
+	}
+	query += " ORDER BY name, id"
+	rows, err := repo.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := make([]models.Supplier, 0)
+	for rows.Next() {
+		var s models.Supplier
+		err := rows.Scan(&s.ID, &s.Name, &s.ContactName, &s.Phone, &s.Email, &s.Address, &s.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, s)
+	}
+
+	return suppliers, rows.Err()
+}
+
+func (repo *SupplierRepository) Create(supplier *models.Supplier) error {
+	query := "INSERT INTO suppliers (name, contact_name, phone, email, address) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	return repo.db.QueryRow(query, supplier.Name, nullIfEmpty(supplier.ContactName), nullIfEmpty(supplier.Phone),
+		nullIfEmpty(supplier.Email), nullIfEmpty(supplier.Address)).Scan(&supplier.ID)
+}
+
+// GetByID - ambil supplier by ID, termasuk yang diarsipkan
+func (repo *SupplierRepository) GetByID(id int) (*models.Supplier, error) {
+	query := "SELECT id, name, COALESCE(contact_name, ''), COALESCE(phone, ''), COALESCE(email, ''), COALESCE(address, ''), archived_at FROM suppliers WHERE id = $1"
+
+	var s models.Supplier
+	err := repo.db.QueryRow(query, id).Scan(&s.ID, &s.Name, &s.ContactName, &s.Phone, &s.Email, &s.Address, &s.ArchivedAt)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrSupplierNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (repo *SupplierRepository) Update(supplier *models.Supplier) error {
+	query := "UPDATE suppliers SET name = $1, contact_name = $2, phone = $3, email = $4, address = $5 WHERE id = $6 RETURNING archived_at"
+	err := repo.db.QueryRow(query, supplier.Name, nullIfEmpty(supplier.ContactName), nullIfEmpty(supplier.Phone),
+		nullIfEmpty(supplier.Email), nullIfEmpty(supplier.Address), supplier.ID).Scan(&supplier.ArchivedAt)
+	if err == sql.ErrNoRows {
+		return models.ErrSupplierNotFound
+	}
+	return err
+}
+
+// Delete - arsipkan supplier (soft delete), purchase order lama tetap menunjuk ke supplier ini
+func (repo *SupplierRepository) Delete(id int) error {
+	result, err := repo.db.Exec("UPDATE suppliers SET archived_at = COALESCE(archived_at, NOW()) WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrSupplierNotFound
+	}
+
+	return nil
+}
+
+// Restore - aktifkan kembali supplier yang diarsipkan
+func (repo *SupplierRepository) Restore(id int) error {
+	result, err := repo.db.Exec("UPDATE suppliers SET archived_at = NULL WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrSupplierNotFound
+	}
+
+	return nil
+}
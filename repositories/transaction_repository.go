@@ -2,7 +2,12 @@ package repositories
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
 
 	"kasir-api/models"
 )
@@ -15,54 +20,1209 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
-func (repo *TransactionRepository) CreateTransaction(items []models.CheckoutItem) (*models.Transaction, error) {
+// CreateTransaction - proses checkout dalam satu DB transaction.
+// Kalau idem tidak nil, key disimpan bersama response supaya retry dengan key yang sama
+// mengembalikan transaksi yang sama tanpa memotong stok lagi.
+func (repo *TransactionRepository) CreateTransaction(req models.CheckoutRequest, idem *models.IdempotencyKey) (*models.Transaction, error) {
 	tx, err := repo.db.Begin()
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	totalAmount := 0
+	if idem != nil {
+		replay, err := claimIdempotencyKey(tx, idem)
+		if err != nil {
+			return nil, err
+		}
+		if replay != nil {
+			idem.Replayed = true
+			return replay, nil
+		}
+	}
+
+	outletID, err := resolveOutlet(tx, req.OutletID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Jumlahkan quantity per produk, item yang sama bisa muncul lebih dari sekali.
+	// Item barcode sudah di-resolve ke product_id oleh service.
+	requested := make(map[int]float64)
+	productIDs := make([]int, 0)
+	for _, item := range req.Items {
+		if _, ok := requested[item.ProductID]; !ok {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		requested[item.ProductID] = models.RoundQuantity(requested[item.ProductID] + item.Quantity)
+	}
+
+	// Lock row produk dengan urutan id yang sama supaya checkout paralel tidak deadlock
+	sort.Ints(productIDs)
+
+	products := make(map[int]cartProduct)
+	shortages := make([]models.StockShortage, 0)
+	// Batch yang masih boleh dijual (belum kadaluarsa) per produk batch-tracked, urut FEFO
+	sellable := make(map[int][]models.BatchAllocation)
+
+	for _, productID := range productIDs {
+		var p cartProduct
+		// Row produk di-lock dulu supaya urutan lock sama, baru stok outlet checkout yang dipakai
+		err := tx.QueryRow(`SELECT name, COALESCE(sku, ''), unit, price, cost_price, category_id, tax_category,
+				archived_at IS NOT NULL, batch_tracked
+			FROM products
+			WHERE id = $1
+			FOR UPDATE`, productID).
+			Scan(&p.name, &p.sku, &p.unit, &p.price, &p.costPrice, &p.categoryID, &p.taxCategory, &p.archived, &p.batched)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: product id %d", models.ErrProductNotFound, productID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.stock, err = lockOutletStock(tx, productID, outletID)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.archived {
+			return nil, fmt.Errorf("%w: product id %d (%s)", models.ErrProductArchived, productID, p.name)
+		}
+
+		if p.unit == models.UnitPcs && requested[productID] != math.Trunc(requested[productID]) {
+			return nil, fmt.Errorf("%w: product id %d is sold per pcs, quantity must be a whole number", models.ErrInvalidQuantity, productID)
+		}
+
+		available := p.stock
+		if p.batched {
+			batches, err := lockProductBatches(tx, outletID, productID, "", true)
+			if err != nil {
+				return nil, err
+			}
+			sellableQty := 0.0
+			for _, b := range batches {
+				sellableQty += b.Quantity
+			}
+			available = math.Min(available, models.RoundQuantity(sellableQty))
+			sellable[productID] = batches
+		}
+
+		if requested[productID] > available {
+			shortages = append(shortages, models.StockShortage{
+				ProductID: productID,
+				Requested: requested[productID],
+				Available: available,
+			})
+		}
+		products[productID] = p
+	}
+
+	if len(shortages) > 0 {
+		return nil, &models.InsufficientStockError{Items: shortages}
+	}
+
+	// Voucher di-lock setelah produk supaya urutan lock selalu sama
+	var voucher *models.Voucher
+	if req.VoucherCode != "" {
+		voucher, err = lockVoucher(tx, req.VoucherCode, req.CustomerRef)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Promo yang diarsipkan tidak ikut, syarat waktu dicek saat evaluasi
+	promotions, err := queryPromotions(tx, "archived_at IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	price, err := priceCart(req, products, promotions, voucher, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	details := price.Items
+	totalAmount := price.TotalAmount
+
+	// Pembulatan tunai menambah/mengurangi tagihan yang harus ditutup pembayaran
+	rounding := req.CashRounding.Adjustment(totalAmount, req.Payments)
+	payments, change, err := models.AllocatePayments(totalAmount+rounding, req.Payments)
+	if err != nil {
+		return nil, err
+	}
+	totalPaid := totalAmount + rounding + change
+
+	var transactionID int
+	var createdAt time.Time
+	cartDiscountType, cartDiscountValue := discountColumns(req.Discount)
+	voucherID := 0
+	if voucher != nil {
+		voucherID = voucher.ID
+	}
+	err = tx.QueryRow(`INSERT INTO transactions (outlet_id, customer_ref, gross_amount, promotion_amount, discount_amount, cart_discount_type,
+			cart_discount_value, cart_discount_amount, voucher_id, voucher_code, voucher_amount, subtotal_amount, tax_inclusive,
+			tax_base_amount, service_charge_amount, tax_amount, total_amount, rounding_amount, total_paid, change_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20) RETURNING id, created_at`,
+		outletID, nullIfEmpty(req.CustomerRef), price.GrossAmount, price.PromotionAmount, price.DiscountAmount, cartDiscountType,
+		cartDiscountValue, price.CartDiscountAmount, nullIfZero(voucherID), nullIfEmpty(price.VoucherCode), price.VoucherAmount,
+		price.SubtotalAmount, price.TaxInclusive, price.TaxBaseAmount, price.ServiceChargeAmount, price.TaxAmount,
+		totalAmount, rounding, totalPaid, change).Scan(&transactionID, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Penukaran dicatat di tx yang sama selagi row voucher masih di-lock
+	if voucher != nil {
+		_, err = tx.Exec("INSERT INTO voucher_redemptions (voucher_id, transaction_id, customer_ref, amount) VALUES ($1, $2, $3, $4)",
+			voucher.ID, transactionID, nullIfEmpty(req.CustomerRef), price.VoucherAmount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Nama dan jenis promo di-snapshot seperti nama produk di detail
+	for _, p := range price.Promotions {
+		_, err = tx.Exec("INSERT INTO transaction_promotions (transaction_id, promotion_id, promotion_name, promotion_type, amount) VALUES ($1, $2, $3, $4, $5)",
+			transactionID, p.PromotionID, p.Name, p.Type, p.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range details {
+		details[i].TransactionID = transactionID
+		discountType, discountValue := discountColumns(details[i].Discount)
+		err = tx.QueryRow(`INSERT INTO transaction_details (transaction_id, product_id, product_name, sku, unit_price, unit_cost, quantity,
+				gross_amount, promotion_amount, discount_type, discount_value, discount_amount, cart_discount_amount, voucher_amount, subtotal,
+				tax_category, tax_rate, tax_base_amount, service_charge_amount, tax_amount, total_amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21) RETURNING id`,
+			transactionID, details[i].ProductID, details[i].ProductName, nullIfEmpty(details[i].SKU), details[i].UnitPrice, details[i].UnitCost,
+			details[i].Quantity, details[i].GrossAmount, details[i].PromotionAmount, discountType, discountValue, details[i].DiscountAmount,
+			details[i].CartDiscountAmount, details[i].VoucherAmount, details[i].Subtotal, details[i].TaxCategory, details[i].TaxRate,
+			details[i].TaxBaseAmount, details[i].ServiceChargeAmount, details[i].TaxAmount, details[i].TotalAmount).Scan(&details[i].ID)
+		if err != nil {
+			return nil, err
+		}
+
+		movement := &models.StockMovement{
+			ProductID:   details[i].ProductID,
+			OutletID:    outletID,
+			Type:        models.StockMovementSale,
+			Quantity:    -details[i].Quantity,
+			ReferenceID: &transactionID,
+		}
+
+		// Produk batch-tracked dipotong FEFO dari batch yang belum kadaluarsa
+		if products[details[i].ProductID].batched {
+			var allocations []models.BatchAllocation
+			allocations, sellable[details[i].ProductID], _ = models.AllocateFEFO(sellable[details[i].ProductID], details[i].Quantity)
+			details[i].Batches = allocations
+			for _, b := range allocations {
+				_, err = tx.Exec("INSERT INTO transaction_detail_batches (transaction_detail_id, batch_id, quantity) VALUES ($1, $2, $3)",
+					details[i].ID, b.BatchID, b.Quantity)
+				if err != nil {
+					return nil, err
+				}
+				b.Quantity = -b.Quantity
+				movement.Batches = append(movement.Batches, b)
+			}
+		}
+
+		err = recordStockMovement(tx, movement)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range payments {
+		payments[i].TransactionID = transactionID
+		err = tx.QueryRow("INSERT INTO transaction_payments (transaction_id, method, amount, tendered, change_amount, reference) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+			transactionID, payments[i].Method, payments[i].Amount, payments[i].Tendered, payments[i].Change, payments[i].Reference).Scan(&payments[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transaction := &models.Transaction{
+		ID:                  transactionID,
+		OutletID:            outletID,
+		CustomerRef:         req.CustomerRef,
+		GrossAmount:         price.GrossAmount,
+		PromotionAmount:     price.PromotionAmount,
+		DiscountAmount:      price.DiscountAmount,
+		CartDiscount:        req.Discount,
+		CartDiscountAmount:  price.CartDiscountAmount,
+		VoucherCode:         price.VoucherCode,
+		VoucherAmount:       price.VoucherAmount,
+		SubtotalAmount:      price.SubtotalAmount,
+		TaxInclusive:        price.TaxInclusive,
+		TaxBaseAmount:       price.TaxBaseAmount,
+		ServiceChargeAmount: price.ServiceChargeAmount,
+		TaxAmount:           price.TaxAmount,
+		TotalAmount:         totalAmount,
+		RoundingAmount:      rounding,
+		TotalPaid:           totalPaid,
+		Change:              change,
+		Status:              models.TransactionStatusCompleted,
+		CreatedAt:           createdAt,
+		Details:             details,
+		Taxes:               price.Taxes,
+		Promotions:          price.Promotions,
+		Payments:            payments,
+	}
+
+	if idem != nil {
+		body, err := json.Marshal(transaction)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec("UPDATE idempotency_keys SET transaction_id = $1, response_body = $2 WHERE key = $3",
+			transactionID, string(body), idem.Key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// cartProduct - data produk yang dipakai untuk menghitung harga dan stok cart
+type cartProduct struct {
+	name        string
+	sku         string
+	unit        string
+	price       int
+	costPrice   int
+	categoryID  *int
+	taxCategory string
+	stock       float64
+	archived    bool
+	batched     bool
+}
+
+// PriceCart - hitung harga cart dengan promo, diskon dan voucher yang berlaku sekarang tanpa membuat transaksi.
+// Stok tidak dicek; row voucher hanya di-lock sebentar untuk cek kuota dan tx selalu di-rollback.
+func (repo *TransactionRepository) PriceCart(req models.CheckoutRequest) (*models.CartPrice, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	outletID, err := resolveOutlet(tx, req.OutletID)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := make(map[int]float64)
+	productIDs := make([]int, 0)
+	for _, item := range req.Items {
+		if _, ok := requested[item.ProductID]; !ok {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		requested[item.ProductID] = models.RoundQuantity(requested[item.ProductID] + item.Quantity)
+	}
+
+	products := make(map[int]cartProduct)
+	for _, productID := range productIDs {
+		quantity := requested[productID]
+		var p cartProduct
+		err := tx.QueryRow(`SELECT name, COALESCE(sku, ''), unit, price, cost_price, category_id, tax_category, archived_at IS NOT NULL
+			FROM products WHERE id = $1`, productID).
+			Scan(&p.name, &p.sku, &p.unit, &p.price, &p.costPrice, &p.categoryID, &p.taxCategory, &p.archived)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: product id %d", models.ErrProductNotFound, productID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.archived {
+			return nil, fmt.Errorf("%w: product id %d (%s)", models.ErrProductArchived, productID, p.name)
+		}
+		if p.unit == models.UnitPcs && quantity != math.Trunc(quantity) {
+			return nil, fmt.Errorf("%w: product id %d is sold per pcs, quantity must be a whole number", models.ErrInvalidQuantity, productID)
+		}
+		products[productID] = p
+	}
+
+	var voucher *models.Voucher
+	if req.VoucherCode != "" {
+		voucher, err = lockVoucher(tx, req.VoucherCode, req.CustomerRef)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	promotions, err := queryPromotions(tx, "archived_at IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	price, err := priceCart(req, products, promotions, voucher, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	price.OutletID = outletID
+	price.CashRoundingAmount = req.CashRounding.Round(price.TotalAmount)
+
+	return price, nil
+}
+
+// priceCart - hitung harga setiap line: promo otomatis dulu, lalu diskon line dari sisa nilai line,
+// lalu diskon keranjang dan voucher yang dibagi ke line sebanding nilainya, terakhir pajak dan service charge
+// dari nilai bersih (Subtotal) tiap line. Batas diskon role hanya berlaku untuk diskon manual, bukan promo atau voucher.
+// voucher boleh nil, kuotanya sudah dicek oleh lockVoucher.
+func priceCart(req models.CheckoutRequest, products map[int]cartProduct, promotions []models.Promotion, voucher *models.Voucher, at time.Time) (*models.CartPrice, error) {
+	lines := make([]models.PromotionLine, 0, len(req.Items))
+	for _, item := range req.Items {
+		p := products[item.ProductID]
+
+		// Label timbangan berisi harga memakai harga dari label, selain itu harga x quantity
+		gross := int(math.Round(float64(p.price) * item.Quantity))
+		if item.FixedSubtotal != nil {
+			gross = *item.FixedSubtotal
+		}
+		lines = append(lines, models.PromotionLine{
+			ProductID:  item.ProductID,
+			CategoryID: p.categoryID,
+			UnitPrice:  p.price,
+			Quantity:   item.Quantity,
+			Amount:     gross,
+		})
+	}
+
+	price := &models.CartPrice{Items: make([]models.TransactionDetail, 0, len(req.Items))}
+	var promotionAmounts []int
+	price.Promotions, promotionAmounts = models.ApplyPromotions(promotions, lines, at)
+
+	lineNets := make([]int, 0, len(req.Items))
+	for i, item := range req.Items {
+		p := products[item.ProductID]
+		gross := lines[i].Amount
+
+		lineDiscount, err := item.Discount.Amount(gross - promotionAmounts[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w (product id %d)", err, item.ProductID)
+		}
+		// Kategori pajak yang dihapus dari konfigurasi setelah produk disimpan
+		taxRate, ok := req.Tax.Rates[p.taxCategory]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s (product id %d)", models.ErrUnknownTaxCategory, p.taxCategory, item.ProductID)
+		}
+		price.GrossAmount += gross
+		price.PromotionAmount += promotionAmounts[i]
+		price.DiscountAmount += lineDiscount
+		lineNets = append(lineNets, gross-promotionAmounts[i]-lineDiscount)
+
+		price.Items = append(price.Items, models.TransactionDetail{
+			ProductID:       item.ProductID,
+			ProductName:     p.name,
+			SKU:             p.sku,
+			UnitPrice:       p.price,
+			UnitCost:        p.costPrice,
+			Quantity:        item.Quantity,
+			GrossAmount:     gross,
+			PromotionAmount: promotionAmounts[i],
+			Discount:        item.Discount,
+			DiscountAmount:  lineDiscount,
+			Subtotal:        gross - promotionAmounts[i] - lineDiscount,
+			TaxCategory:     p.taxCategory,
+			TaxRate:         taxRate,
+		})
+	}
+
+	cartDiscount, err := req.Discount.Amount(price.GrossAmount - price.PromotionAmount - price.DiscountAmount)
+	if err != nil {
+		return nil, err
+	}
+	for i, share := range models.AllocateDiscount(cartDiscount, lineNets) {
+		price.Items[i].CartDiscountAmount = share
+		price.Items[i].Subtotal -= share
+	}
+	price.CartDiscountAmount = cartDiscount
+	price.DiscountAmount += cartDiscount
+
+	maxDiscount := int(math.Ceil(float64(price.GrossAmount) * req.MaxDiscountPercent / 100))
+	if price.DiscountAmount > maxDiscount {
+		return nil, fmt.Errorf("%w: discount %d is above %g%% of %d", models.ErrDiscountLimitExceeded, price.DiscountAmount, req.MaxDiscountPercent, price.GrossAmount)
+	}
+	price.TotalAmount = price.GrossAmount - price.PromotionAmount - price.DiscountAmount
+
+	// Minimum belanja voucher dibandingkan dengan total setelah promo dan diskon manual
+	if voucher != nil {
+		if err := voucher.CheckApplicable(at, price.TotalAmount); err != nil {
+			return nil, err
+		}
+		nets := make([]int, len(price.Items))
+		for i := range price.Items {
+			nets[i] = price.Items[i].Subtotal
+		}
+		price.VoucherCode = voucher.Code
+		price.VoucherAmount = voucher.Amount(price.TotalAmount)
+		for i, share := range models.AllocateDiscount(price.VoucherAmount, nets) {
+			price.Items[i].VoucherAmount = share
+			price.Items[i].Subtotal -= share
+		}
+		price.TotalAmount -= price.VoucherAmount
+	}
+
+	// Grand total = DPP + pajak; untuk harga termasuk pajak sama dengan subtotal kalau tanpa service charge
+	price.SubtotalAmount = price.TotalAmount
+	price.TaxInclusive = req.Tax.Inclusive
+	req.Tax.ApplyTax(price.Items)
+	for _, item := range price.Items {
+		price.TaxBaseAmount += item.TaxBaseAmount
+		price.ServiceChargeAmount += item.ServiceChargeAmount
+		price.TaxAmount += item.TaxAmount
+	}
+	price.TotalAmount = price.TaxBaseAmount + price.TaxAmount
+	price.Taxes = models.SummarizeTaxes(price.Items)
+
+	return price, nil
+}
+
+// claimIdempotencyKey - daftarkan key di dalam tx. Kalau key sudah pernah dipakai dan belum
+// expired, kembalikan transaksi yang tersimpan (replay) atau ErrIdempotencyKeyReused kalau
+// body request berbeda. Insert ke unique key akan menunggu checkout lain dengan key yang sama
+// sampai commit/rollback, jadi retry yang paralel tidak bisa membuat dua transaksi.
+func claimIdempotencyKey(tx *sql.Tx, idem *models.IdempotencyKey) (*models.Transaction, error) {
+	_, err := tx.Exec("DELETE FROM idempotency_keys WHERE key = $1 AND expires_at <= NOW()", idem.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec("INSERT INTO idempotency_keys (key, request_hash, expires_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING",
+		idem.Key, idem.RequestHash, idem.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if inserted == 1 {
+		return nil, nil
+	}
+
+	var requestHash string
+	var responseBody sql.NullString
+	err = tx.QueryRow("SELECT request_hash, response_body FROM idempotency_keys WHERE key = $1", idem.Key).Scan(&requestHash, &responseBody)
+	if err != nil {
+		return nil, err
+	}
+	if !responseBody.Valid {
+		if requestHash != idem.RequestHash {
+			return nil, models.ErrIdempotencyKeyReused
+		}
+		return nil, fmt.Errorf("idempotency key %s has no stored response", idem.Key)
+	}
+
+	return replayIdempotencyKey(idem, requestHash, responseBody.String)
+}
+
+// FindIdempotentTransaction - cek key sebelum cart diproses. Kalau key belum expired dan responnya
+// sudah tersimpan, kembalikan transaksi itu (replay) atau ErrIdempotencyKeyReused kalau body berbeda.
+// Key yang belum ada atau checkout-nya masih berjalan mengembalikan nil; claimIdempotencyKey
+// di CreateTransaction tetap yang menjaga retry paralel.
+func (repo *TransactionRepository) FindIdempotentTransaction(idem *models.IdempotencyKey) (*models.Transaction, error) {
+	var requestHash string
+	var responseBody string
+	err := repo.db.QueryRow(`SELECT request_hash, response_body FROM idempotency_keys
+		WHERE key = $1 AND expires_at > NOW() AND response_body IS NOT NULL`, idem.Key).Scan(&requestHash, &responseBody)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := replayIdempotencyKey(idem, requestHash, responseBody)
+	if err != nil {
+		return nil, err
+	}
+	idem.Replayed = true
+	return transaction, nil
+}
+
+// replayIdempotencyKey - transaksi tersimpan untuk key, hanya kalau body request-nya sama
+func replayIdempotencyKey(idem *models.IdempotencyKey, requestHash, responseBody string) (*models.Transaction, error) {
+	if requestHash != idem.RequestHash {
+		return nil, models.ErrIdempotencyKeyReused
+	}
+
+	var transaction models.Transaction
+	if err := json.Unmarshal([]byte(responseBody), &transaction); err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// GetByID - ambil transaksi beserta detailnya
+func (repo *TransactionRepository) GetByID(id int) (*models.Transaction, error) {
+	var t models.Transaction
+	var cartDiscountType sql.NullString
+	var cartDiscountValue sql.NullFloat64
+	err := repo.db.QueryRow(`SELECT id, outlet_id, COALESCE(customer_ref, ''), gross_amount, promotion_amount, discount_amount, cart_discount_type,
+			cart_discount_value, cart_discount_amount, COALESCE(voucher_code, ''), voucher_amount, subtotal_amount, tax_inclusive, tax_base_amount,
+			service_charge_amount, tax_amount, total_amount, rounding_amount, total_paid, change_amount, status, created_at
+		FROM transactions WHERE id = $1`, id).
+		Scan(&t.ID, &t.OutletID, &t.CustomerRef, &t.GrossAmount, &t.PromotionAmount, &t.DiscountAmount, &cartDiscountType,
+			&cartDiscountValue, &t.CartDiscountAmount, &t.VoucherCode, &t.VoucherAmount, &t.SubtotalAmount, &t.TaxInclusive, &t.TaxBaseAmount,
+			&t.ServiceChargeAmount, &t.TaxAmount, &t.TotalAmount, &t.RoundingAmount, &t.TotalPaid, &t.Change, &t.Status, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.CartDiscount = discountFromColumns(cartDiscountType, cartDiscountValue)
+
+	details, err := repo.getDetails([]int{t.ID})
+	if err != nil {
+		return nil, err
+	}
+	t.Details = details[t.ID]
+	if t.Details == nil {
+		t.Details = make([]models.TransactionDetail, 0)
+	}
+	t.Taxes = models.SummarizeTaxes(t.Details)
+
+	promotions, err := repo.getPromotions([]int{t.ID})
+	if err != nil {
+		return nil, err
+	}
+	t.Promotions = promotions[t.ID]
+	if t.Promotions == nil {
+		t.Promotions = make([]models.AppliedPromotion, 0)
+	}
+
+	payments, err := repo.getPayments([]int{t.ID})
+	if err != nil {
+		return nil, err
+	}
+	t.Payments = payments[t.ID]
+	if t.Payments == nil {
+		t.Payments = make([]models.Payment, 0)
+	}
+
+	t.Refunds, err = repo.getRefunds(t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// List - daftar transaksi (terbaru dulu) dengan filter dan pagination
+func (repo *TransactionRepository) List(filter models.TransactionFilter) ([]models.Transaction, int, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.StartDate != "" {
+		addCondition("DATE(t.created_at) >= $%d", filter.StartDate)
+	}
+	if filter.EndDate != "" {
+		addCondition("DATE(t.created_at) <= $%d", filter.EndDate)
+	}
+	if filter.MinTotal != nil {
+		addCondition("t.total_amount >= $%d", *filter.MinTotal)
+	}
+	if filter.MaxTotal != nil {
+		addCondition("t.total_amount <= $%d", *filter.MaxTotal)
+	}
+	if filter.OutletID != 0 {
+		addCondition("t.outlet_id = $%d", filter.OutletID)
+	}
+	if filter.ProductID != 0 {
+		addCondition("EXISTS (SELECT 1 FROM transaction_details td WHERE td.transaction_id = t.id AND td.product_id = $%d)", filter.ProductID)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM transactions t"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`SELECT t.id, t.outlet_id, COALESCE(t.customer_ref, ''), t.gross_amount, t.promotion_amount, t.discount_amount,
+			t.cart_discount_type, t.cart_discount_value, t.cart_discount_amount, COALESCE(t.voucher_code, ''), t.voucher_amount,
+			t.subtotal_amount, t.tax_inclusive, t.tax_base_amount, t.service_charge_amount, t.tax_amount,
+			t.total_amount, t.rounding_amount, t.total_paid, t.change_amount, t.status, t.created_at
+		FROM transactions t%s ORDER BY t.created_at DESC, t.id DESC LIMIT $%d OFFSET $%d`,
+		where, len(args)+1, len(args)+2)
+	rows, err := repo.db.Query(query, append(args, filter.Limit, (filter.Page-1)*filter.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	transactions := make([]models.Transaction, 0)
+	ids := make([]int, 0)
+	for rows.Next() {
+		var t models.Transaction
+		var cartDiscountType sql.NullString
+		var cartDiscountValue sql.NullFloat64
+		err := rows.Scan(&t.ID, &t.OutletID, &t.CustomerRef, &t.GrossAmount, &t.PromotionAmount, &t.DiscountAmount,
+			&cartDiscountType, &cartDiscountValue, &t.CartDiscountAmount, &t.VoucherCode, &t.VoucherAmount,
+			&t.SubtotalAmount, &t.TaxInclusive, &t.TaxBaseAmount, &t.ServiceChargeAmount, &t.TaxAmount,
+			&t.TotalAmount, &t.RoundingAmount, &t.TotalPaid, &t.Change, &t.Status, &t.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		t.CartDiscount = discountFromColumns(cartDiscountType, cartDiscountValue)
+		transactions = append(transactions, t)
+		ids = append(ids, t.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	details, err := repo.getDetails(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	promotions, err := repo.getPromotions(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	payments, err := repo.getPayments(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range transactions {
+		transactions[i].Details = details[transactions[i].ID]
+		if transactions[i].Details == nil {
+			transactions[i].Details = make([]models.TransactionDetail, 0)
+		}
+		transactions[i].Taxes = models.SummarizeTaxes(transactions[i].Details)
+		transactions[i].Promotions = promotions[transactions[i].ID]
+		if transactions[i].Promotions == nil {
+			transactions[i].Promotions = make([]models.AppliedPromotion, 0)
+		}
+		transactions[i].Payments = payments[transactions[i].ID]
+		if transactions[i].Payments == nil {
+			transactions[i].Payments = make([]models.Payment, 0)
+		}
+	}
+
+	return transactions, total, nil
+}
+
+// getDetails - ambil detail untuk beberapa transaksi sekaligus, dikelompokkan per transaction id
+func (repo *TransactionRepository) getDetails(transactionIDs []int) (map[int][]models.TransactionDetail, error) {
+	result := make(map[int][]models.TransactionDetail)
+	if len(transactionIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders, args := inPlaceholders(transactionIDs)
+	query := `
+		SELECT td.id, td.transaction_id, COALESCE(td.product_id, 0), td.product_name, COALESCE(td.sku, ''),
+			td.unit_price, td.unit_cost, td.quantity, td.gross_amount, td.promotion_amount, td.discount_type, td.discount_value,
+			td.discount_amount, td.cart_discount_amount, td.voucher_amount, td.subtotal, COALESCE(td.tax_category, ''), td.tax_rate,
+			td.tax_base_amount, td.service_charge_amount, td.tax_amount, td.total_amount
+		FROM transaction_details td
+		WHERE td.transaction_id IN (` + placeholders + `)
+		ORDER BY td.id`
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
 	details := make([]models.TransactionDetail, 0)
+	for rows.Next() {
+		var d models.TransactionDetail
+		var discountType sql.NullString
+		var discountValue sql.NullFloat64
+		err := rows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &d.ProductName, &d.SKU, &d.UnitPrice, &d.UnitCost, &d.Quantity,
+			&d.GrossAmount, &d.PromotionAmount, &discountType, &discountValue, &d.DiscountAmount, &d.CartDiscountAmount, &d.VoucherAmount, &d.Subtotal,
+			&d.TaxCategory, &d.TaxRate, &d.TaxBaseAmount, &d.ServiceChargeAmount, &d.TaxAmount, &d.TotalAmount)
+		if err != nil {
+			return nil, err
+		}
+		d.Discount = discountFromColumns(discountType, discountValue)
+		details = append(details, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	for _, item := range items {
-		var productPrice, stock int
-		var productName string
+	batches, err := repo.getDetailBatches(transactionIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range details {
+		d.Batches = batches[d.ID]
+		result[d.TransactionID] = append(result[d.TransactionID], d)
+	}
 
-		err := tx.QueryRow("SELECT name, price, stock FROM products WHERE id = $1", item.ProductID).Scan(&productName, &productPrice, &stock)
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("product id %d not found", item.ProductID)
+	return result, nil
+}
+
+// discountColumns - nilai kolom discount_type/discount_value, NULL kalau tidak ada diskon
+func discountColumns(discount *models.Discount) (interface{}, interface{}) {
+	if discount == nil {
+		return nil, nil
+	}
+	return discount.Type, discount.Value
+}
+
+// discountFromColumns - kebalikan discountColumns
+func discountFromColumns(discountType sql.NullString, discountValue sql.NullFloat64) *models.Discount {
+	if !discountType.Valid {
+		return nil
+	}
+	return &models.Discount{Type: discountType.String, Value: discountValue.Float64}
+}
+
+// getDetailBatches - batch yang dipakai detail transaksi, dikelompokkan per detail id
+func (repo *TransactionRepository) getDetailBatches(transactionIDs []int) (map[int][]models.BatchAllocation, error) {
+	placeholders, args := inPlaceholders(transactionIDs)
+	rows, err := repo.db.Query(`
+		SELECT tdb.transaction_detail_id, b.id, b.batch_number, TO_CHAR(b.expiry_date, 'YYYY-MM-DD'), tdb.quantity
+		FROM transaction_detail_batches tdb
+		JOIN transaction_details td ON td.id = tdb.transaction_detail_id
+		JOIN product_batches b ON b.id = tdb.batch_id
+		WHERE td.transaction_id IN (`+placeholders+`)
+		ORDER BY tdb.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int][]models.BatchAllocation)
+	for rows.Next() {
+		var detailID int
+		var b models.BatchAllocation
+		if err := rows.Scan(&detailID, &b.BatchID, &b.BatchNumber, &b.ExpiryDate, &b.Quantity); err != nil {
+			return nil, err
+		}
+		result[detailID] = append(result[detailID], b)
+	}
+
+	return result, rows.Err()
+}
+
+// getPromotions - promo yang berlaku untuk beberapa transaksi sekaligus, dikelompokkan per transaction id
+func (repo *TransactionRepository) getPromotions(transactionIDs []int) (map[int][]models.AppliedPromotion, error) {
+	result := make(map[int][]models.AppliedPromotion)
+	if len(transactionIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders, args := inPlaceholders(transactionIDs)
+	rows, err := repo.db.Query(`
+		SELECT transaction_id, promotion_id, promotion_name, promotion_type, amount
+		FROM transaction_promotions
+		WHERE transaction_id IN (`+placeholders+`)
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactionID int
+		var p models.AppliedPromotion
+		if err := rows.Scan(&transactionID, &p.PromotionID, &p.Name, &p.Type, &p.Amount); err != nil {
+			return nil, err
+		}
+		result[transactionID] = append(result[transactionID], p)
+	}
+
+	return result, rows.Err()
+}
+
+// getPayments - ambil pembayaran untuk beberapa transaksi sekaligus, dikelompokkan per transaction id
+func (repo *TransactionRepository) getPayments(transactionIDs []int) (map[int][]models.Payment, error) {
+	result := make(map[int][]models.Payment)
+	if len(transactionIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders, args := inPlaceholders(transactionIDs)
+	rows, err := repo.db.Query(`
+		SELECT id, transaction_id, method, amount, tendered, change_amount, COALESCE(reference, '')
+		FROM transaction_payments
+		WHERE transaction_id IN (`+placeholders+`)
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Payment
+		err := rows.Scan(&p.ID, &p.TransactionID, &p.Method, &p.Amount, &p.Tendered, &p.Change, &p.Reference)
+		if err != nil {
+			return nil, err
+		}
+		result[p.TransactionID] = append(result[p.TransactionID], p)
+	}
+
+	return result, rows.Err()
+}
+
+// inPlaceholders - buat "$1, $2, ..." beserta args untuk klausa IN
+func inPlaceholders(ids []int) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// nullIfZero - simpan id 0 sebagai NULL untuk foreign key opsional
+func nullIfZero(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// getRefunds - ambil semua refund/void untuk satu transaksi beserta item-nya
+func (repo *TransactionRepository) getRefunds(transactionID int) ([]models.Refund, error) {
+	rows, err := repo.db.Query(`
+		SELECT id, transaction_id, type, reason, amount, tax_amount, rounding_amount, created_at
+		FROM refunds
+		WHERE transaction_id = $1
+		ORDER BY id`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := make([]models.Refund, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var r models.Refund
+		if err := rows.Scan(&r.ID, &r.TransactionID, &r.Type, &r.Reason, &r.Amount, &r.TaxAmount, &r.RoundingAmount, &r.CreatedAt); err != nil {
+			return nil, err
 		}
+		r.Items = make([]models.RefundItem, 0)
+		r.Payments = make([]models.RefundPayment, 0)
+		index[r.ID] = len(refunds)
+		refunds = append(refunds, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	itemRows, err := repo.db.Query(`
+		SELECT ri.id, ri.refund_id, ri.transaction_detail_id, COALESCE(ri.product_id, 0), ri.quantity, ri.amount,
+			ri.tax_amount, ri.service_charge_amount
+		FROM refund_items ri
+		JOIN refunds r ON ri.refund_id = r.id
+		WHERE r.transaction_id = $1
+		ORDER BY ri.id`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item models.RefundItem
+		err := itemRows.Scan(&item.ID, &item.RefundID, &item.TransactionDetailID, &item.ProductID, &item.Quantity, &item.Amount,
+			&item.TaxAmount, &item.ServiceChargeAmount)
 		if err != nil {
 			return nil, err
 		}
-  
-		subtotal := productPrice * item.Quantity
-		totalAmount += subtotal
+		r := &refunds[index[item.RefundID]]
+		r.Items = append(r.Items, item)
+	}
+	if err := itemRows.Err(); err != nil {
+		return nil, err
+	}
+
+	paymentRows, err := repo.db.Query(`
+		SELECT rp.refund_id, rp.method, rp.amount
+		FROM refund_payments rp
+		JOIN refunds r ON rp.refund_id = r.id
+		WHERE r.transaction_id = $1
+		ORDER BY rp.id`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer paymentRows.Close()
+
+	for paymentRows.Next() {
+		var refundID int
+		var payment models.RefundPayment
+		if err := paymentRows.Scan(&refundID, &payment.Method, &payment.Amount); err != nil {
+			return nil, err
+		}
+		r := &refunds[index[refundID]]
+		r.Payments = append(r.Payments, payment)
+	}
+
+	return refunds, paymentRows.Err()
+}
+
+// refundablePayments - sisa pembayaran per metode yang belum dikembalikan refund sebelumnya,
+// urut dari metode yang dibayar terakhir
+func refundablePayments(tx *sql.Tx, transactionID int) ([]models.RefundPayment, error) {
+	rows, err := tx.Query(`
+		SELECT tp.method, SUM(tp.amount) - COALESCE((
+			SELECT SUM(rp.amount)
+			FROM refund_payments rp
+			JOIN refunds r ON rp.refund_id = r.id
+			WHERE r.transaction_id = $1 AND rp.method = tp.method
+		), 0)
+		FROM transaction_payments tp
+		WHERE tp.transaction_id = $1
+		GROUP BY tp.method
+		ORDER BY MAX(tp.id) DESC`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := make([]models.RefundPayment, 0)
+	for rows.Next() {
+		var p models.RefundPayment
+		if err := rows.Scan(&p.Method, &p.Amount); err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// refundableLine - sisa quantity & nilai yang masih bisa di-refund dari satu detail transaksi.
+// Nilai line adalah total_amount (termasuk pajak dan service charge).
+type refundableLine struct {
+	productID              int
+	quantity               float64
+	remainingQty           float64
+	total                  int
+	remainingAmount        int
+	tax                    int
+	remainingTax           int
+	serviceCharge          int
+	remainingServiceCharge int
+}
+
+// refundShare - bagian value yang di-refund untuk quantity. Line yang di-refund habis mengambil sisa nilai
+// supaya tidak ada selisih pembulatan.
+func (line *refundableLine) refundShare(value, remaining int, quantity float64) int {
+	if quantity == line.remainingQty {
+		return remaining
+	}
+	return int(math.Round(float64(value) * quantity / line.quantity))
+}
+
+// VoidTransaction - batalkan seluruh sisa transaksi dan kembalikan stoknya
+func (repo *TransactionRepository) VoidTransaction(transactionID int, reason string) (*models.Refund, error) {
+	return repo.refund(transactionID, models.RefundTypeVoid, reason, nil)
+}
+
+// RefundTransaction - refund sebagian line transaksi dan kembalikan stoknya
+func (repo *TransactionRepository) RefundTransaction(transactionID int, req models.RefundRequest) (*models.Refund, error) {
+	return repo.refund(transactionID, models.RefundTypeRefund, req.Reason, req.Items)
+}
+
+// refund - catat refund dan kembalikan stok dalam satu DB transaction.
+// items nil berarti semua sisa quantity (void).
+func (repo *TransactionRepository) refund(transactionID int, refundType, reason string, items []models.RefundRequestItem) (*models.Refund, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock transaksi supaya dua refund paralel tidak me-refund line yang sama dua kali
+	var status string
+	var outletID, rounding int
+	err = tx.QueryRow("SELECT status, outlet_id, rounding_amount FROM transactions WHERE id = $1 FOR UPDATE", transactionID).
+		Scan(&status, &outletID, &rounding)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == models.TransactionStatusVoided || status == models.TransactionStatusRefunded {
+		return nil, fmt.Errorf("%w: transaction is already %s", models.ErrRefundNotAllowed, status)
+	}
+
+	rows, err := tx.Query(`
+		SELECT td.id, COALESCE(td.product_id, 0), td.quantity, td.total_amount, td.tax_amount, td.service_charge_amount,
+			td.quantity - COALESCE(SUM(ri.quantity), 0),
+			td.total_amount - COALESCE(SUM(ri.amount), 0),
+			td.tax_amount - COALESCE(SUM(ri.tax_amount), 0),
+			td.service_charge_amount - COALESCE(SUM(ri.service_charge_amount), 0)
+		FROM transaction_details td
+		LEFT JOIN refund_items ri ON ri.transaction_detail_id = td.id
+		WHERE td.transaction_id = $1
+		GROUP BY td.id, td.product_id, td.quantity, td.total_amount, td.tax_amount, td.service_charge_amount
+		ORDER BY td.id`, transactionID)
+	if err != nil {
+		return nil, err
+	}
 
-		_, err = tx.Exec("UPDATE products SET stock = stock - $1 WHERE id = $2", item.Quantity, item.ProductID)
+	lines := make(map[int]*refundableLine)
+	lineIDs := make([]int, 0)
+	for rows.Next() {
+		var id int
+		var line refundableLine
+		err := rows.Scan(&id, &line.productID, &line.quantity, &line.total, &line.tax, &line.serviceCharge,
+			&line.remainingQty, &line.remainingAmount, &line.remainingTax, &line.remainingServiceCharge)
 		if err != nil {
+			rows.Close()
 			return nil, err
 		}
+		lines[id] = &line
+		lineIDs = append(lineIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if items == nil {
+		items = make([]models.RefundRequestItem, 0)
+		for _, id := range lineIDs {
+			if lines[id].remainingQty > 0 {
+				items = append(items, models.RefundRequestItem{TransactionDetailID: id, Quantity: lines[id].remainingQty})
+			}
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: nothing left to refund", models.ErrRefundNotAllowed)
+	}
+
+	refund := &models.Refund{
+		TransactionID: transactionID,
+		Type:          refundType,
+		Reason:        reason,
+		Items:         make([]models.RefundItem, 0, len(items)),
+	}
+
+	for _, item := range items {
+		line, ok := lines[item.TransactionDetailID]
+		if !ok {
+			return nil, fmt.Errorf("%w: detail id %d does not belong to transaction %d", models.ErrRefundNotAllowed, item.TransactionDetailID, transactionID)
+		}
+		if item.Quantity > line.remainingQty {
+			return nil, fmt.Errorf("%w: detail id %d only has %g left to refund", models.ErrRefundNotAllowed, item.TransactionDetailID, line.remainingQty)
+		}
+
+		// Nilai refund, pajak dan service charge proporsional terhadap quantity line
+		amount := line.refundShare(line.total, line.remainingAmount, item.Quantity)
+		tax := line.refundShare(line.tax, line.remainingTax, item.Quantity)
+		serviceCharge := line.refundShare(line.serviceCharge, line.remainingServiceCharge, item.Quantity)
+		line.remainingQty = models.RoundQuantity(line.remainingQty - item.Quantity)
+		line.remainingAmount -= amount
+		line.remainingTax -= tax
+		line.remainingServiceCharge -= serviceCharge
+		refund.Amount += amount
+		refund.TaxAmount += tax
 
-		details = append(details, models.TransactionDetail{
-			ProductID:   item.ProductID,
-			ProductName: productName,
-			Quantity:    item.Quantity,
-			Subtotal:    subtotal,
+		refund.Items = append(refund.Items, models.RefundItem{
+			TransactionDetailID: item.TransactionDetailID,
+			ProductID:           line.productID,
+			Quantity:            item.Quantity,
+			Amount:              amount,
+			TaxAmount:           tax,
+			ServiceChargeAmount: serviceCharge,
 		})
 	}
 
-	var transactionID int
-	err = tx.QueryRow("INSERT INTO transactions (total_amount) VALUES ($1) RETURNING id", totalAmount).Scan(&transactionID)
+	status = models.TransactionStatusRefunded
+	for _, line := range lines {
+		if line.remainingQty > 0 {
+			status = models.TransactionStatusPartiallyRefunded
+			break
+		}
+	}
+	if refundType == models.RefundTypeVoid {
+		status = models.TransactionStatusVoided
+	}
+
+	// Refund yang menghabiskan transaksi ikut membalik pembulatan tunai, jadi total yang dikembalikan
+	// sama dengan yang dibayar. Pembulatan ke bawah tidak boleh membuat refund minus.
+	if status != models.TransactionStatusPartiallyRefunded {
+		refund.RoundingAmount = rounding
+		if refund.Amount+refund.RoundingAmount < 0 {
+			refund.RoundingAmount = -refund.Amount
+		}
+	}
+
+	err = tx.QueryRow(`INSERT INTO refunds (transaction_id, type, reason, amount, tax_amount, rounding_amount)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		transactionID, refund.Type, refund.Reason, refund.Amount, refund.TaxAmount, refund.RoundingAmount).Scan(&refund.ID, &refund.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 
-	for i := range details {
-		details[i].TransactionID = transactionID
-		_, err = tx.Exec("INSERT INTO transaction_details (transaction_id, product_id, quantity, subtotal) VALUES ($1, $2, $3, $4)",
-			transactionID, details[i].ProductID, details[i].Quantity, details[i].Subtotal)
+	// Uang dikembalikan lewat metode pembayaran asal supaya laporan per metode (laci kas) tetap cocok
+	refundable, err := refundablePayments(tx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	refund.Payments = models.AllocateRefund(refund.Amount+refund.RoundingAmount, refundable)
+	for _, p := range refund.Payments {
+		_, err = tx.Exec("INSERT INTO refund_payments (refund_id, method, amount) VALUES ($1, $2, $3)", refund.ID, p.Method, p.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range refund.Items {
+		refund.Items[i].RefundID = refund.ID
+		err = tx.QueryRow(`INSERT INTO refund_items (refund_id, transaction_detail_id, product_id, quantity, amount, tax_amount, service_charge_amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			refund.ID, refund.Items[i].TransactionDetailID, nullIfZero(refund.Items[i].ProductID), refund.Items[i].Quantity, refund.Items[i].Amount,
+			refund.Items[i].TaxAmount, refund.Items[i].ServiceChargeAmount).Scan(&refund.Items[i].ID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Produk yang sudah dihapus tidak punya stok untuk dikembalikan
+		if refund.Items[i].ProductID != 0 {
+			// Stok kembali ke outlet tempat transaksi terjadi
+			movement := &models.StockMovement{
+				ProductID:   refund.Items[i].ProductID,
+				OutletID:    outletID,
+				Type:        models.StockMovementRefund,
+				Quantity:    refund.Items[i].Quantity,
+				Reason:      refund.Reason,
+				ReferenceID: &refund.ID,
+			}
+			movement.Batches, err = returnDetailBatches(tx, refund.Items[i].TransactionDetailID, refund.Items[i].Quantity)
+			if err != nil {
+				return nil, err
+			}
+
+			err = recordStockMovement(tx, movement)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	_, err = tx.Exec("UPDATE transactions SET status = $1 WHERE id = $2", status, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transaksi yang dibatalkan seluruhnya mengembalikan kuota voucher yang dipakai
+	if status == models.TransactionStatusVoided || status == models.TransactionStatusRefunded {
+		_, err = tx.Exec("UPDATE voucher_redemptions SET released_at = NOW() WHERE transaction_id = $1 AND released_at IS NULL", transactionID)
 		if err != nil {
 			return nil, err
 		}
@@ -72,78 +1232,124 @@ func (repo *TransactionRepository) CreateTransaction(items []models.CheckoutItem
 		return nil, err
 	}
 
-	return &models.Transaction{
-		ID:          transactionID,
-		TotalAmount: totalAmount,
-		Details:     details,
-	}, nil
+	return refund, nil
 }
 
-// GetSalesSummaryToday - mendapatkan ringkasan penjualan hari ini
-func (repo *TransactionRepository) GetSalesSummaryToday() (*models.SalesSummary, error) {
-	summary := &models.SalesSummary{}
-
-	// Get total revenue dan total transaksi hari ini
-	err := repo.db.QueryRow(`
-		SELECT COALESCE(SUM(total_amount), 0), COUNT(*) 
-		FROM transactions 
-		WHERE DATE(created_at) = CURRENT_DATE
-	`).Scan(&summary.TotalRevenue, &summary.TotalTransaksi)
+// returnDetailBatches - kembalikan quantity refund ke batch asal detail transaksi, mulai dari batch
+// dengan kadaluarsa paling akhir. Detail tanpa catatan batch mengembalikan nil (ledger yang memilih batch).
+func returnDetailBatches(tx *sql.Tx, detailID int, quantity float64) ([]models.BatchAllocation, error) {
+	rows, err := tx.Query(`
+		SELECT tdb.id, b.id, b.batch_number, TO_CHAR(b.expiry_date, 'YYYY-MM-DD'), tdb.quantity - tdb.returned_quantity
+		FROM transaction_detail_batches tdb
+		JOIN product_batches b ON b.id = tdb.batch_id
+		WHERE tdb.transaction_detail_id = $1 AND tdb.quantity > tdb.returned_quantity
+		ORDER BY b.expiry_date DESC NULLS FIRST, b.id DESC
+		FOR UPDATE OF tdb`, detailID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get produk terlaris hari ini
-	var bestSeller models.BestSeller
-	err = repo.db.QueryRow(`
-		SELECT p.name, COALESCE(SUM(td.quantity), 0) as qty_terjual
-		FROM transaction_details td
-		JOIN transactions t ON td.transaction_id = t.id
-		JOIN products p ON td.product_id = p.id
-		WHERE DATE(t.created_at) = CURRENT_DATE
-		GROUP BY p.id, p.name
-		ORDER BY qty_terjual DESC
-		LIMIT 1
-	`).Scan(&bestSeller.Nama, &bestSeller.QtyTerjual)
-	
-	if err == sql.ErrNoRows {
-		summary.ProdukTerlaris = nil
-	} else if err != nil {
+	rowIDs := make([]int, 0)
+	consumed := make([]models.BatchAllocation, 0)
+	for rows.Next() {
+		var rowID int
+		var b models.BatchAllocation
+		if err := rows.Scan(&rowID, &b.BatchID, &b.BatchNumber, &b.ExpiryDate, &b.Quantity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rowIDs = append(rowIDs, rowID)
+		consumed = append(consumed, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
 		return nil, err
-	} else {
-		summary.ProdukTerlaris = &bestSeller
+	}
+	if len(consumed) == 0 {
+		return nil, nil
 	}
 
-	return summary, nil
+	allocations, _, shortfall := models.AllocateFEFO(consumed, quantity)
+	if shortfall > 0 {
+		// Catatan batch kurang dari quantity line (produk baru batch-tracked setelah dijual)
+		allocations[0].Quantity = models.RoundQuantity(allocations[0].Quantity + shortfall)
+	}
+	for i, b := range allocations {
+		returned := math.Min(b.Quantity, consumed[i].Quantity)
+		_, err := tx.Exec("UPDATE transaction_detail_batches SET returned_quantity = returned_quantity + $1 WHERE id = $2", returned, rowIDs[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return allocations, nil
+}
+
+// GetSalesSummaryToday - mendapatkan ringkasan penjualan hari ini, outletID 0 berarti semua outlet
+func (repo *TransactionRepository) GetSalesSummaryToday(outletID int) (*models.SalesSummary, error) {
+	return repo.getSalesSummary(outletID, "DATE(t.created_at) = CURRENT_DATE", "DATE(r.created_at) = CURRENT_DATE")
+}
+
+// GetSalesSummaryByDateRange - mendapatkan ringkasan penjualan berdasarkan rentang tanggal, outletID 0 berarti semua outlet
+func (repo *TransactionRepository) GetSalesSummaryByDateRange(startDate, endDate string, outletID int) (*models.SalesSummary, error) {
+	return repo.getSalesSummary(outletID, "DATE(t.created_at) >= $1 AND DATE(t.created_at) <= $2",
+		"DATE(r.created_at) >= $1 AND DATE(r.created_at) <= $2", startDate, endDate)
 }
 
-// GetSalesSummaryByDateRange - mendapatkan ringkasan penjualan berdasarkan rentang tanggal
-func (repo *TransactionRepository) GetSalesSummaryByDateRange(startDate, endDate string) (*models.SalesSummary, error) {
+// getSalesSummary - susun laporan penjualan. Penjualan difilter dengan salesCondition (alias t),
+// refund dengan refundCondition (alias r). Kalau outletID diisi keduanya dibatasi ke outlet itu,
+// kalau tidak laporan menggabungkan semua outlet dan menyertakan rincian per outlet.
+func (repo *TransactionRepository) getSalesSummary(outletID int, salesCondition, refundCondition string, args ...interface{}) (*models.SalesSummary, error) {
 	summary := &models.SalesSummary{}
+	if outletID != 0 {
+		args = append(args, outletID)
+		salesCondition += fmt.Sprintf(" AND t.outlet_id = $%d", len(args))
+		refundCondition += fmt.Sprintf(" AND r.transaction_id IN (SELECT id FROM transactions WHERE outlet_id = $%d)", len(args))
+		summary.OutletID = &outletID
+	}
 
-	// Get total revenue dan total transaksi dalam rentang tanggal
+	// Total revenue, pembulatan tunai dan total transaksi dalam periode
 	err := repo.db.QueryRow(`
-		SELECT COALESCE(SUM(total_amount), 0), COUNT(*) 
-		FROM transactions 
-		WHERE DATE(created_at) >= $1 AND DATE(created_at) <= $2
-	`, startDate, endDate).Scan(&summary.TotalRevenue, &summary.TotalTransaksi)
+		SELECT COALESCE(SUM(t.total_amount), 0), COALESCE(SUM(t.rounding_amount), 0), COUNT(*)
+		FROM transactions t
+		WHERE `+salesCondition, args...).Scan(&summary.TotalRevenue, &summary.TotalRounding, &summary.TotalTransaksi)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refund dalam periode mengurangi omzet dan pembulatan periode itu
+	var refundedRounding int
+	err = repo.db.QueryRow(`
+		SELECT COALESCE(SUM(r.amount), 0), COALESCE(SUM(r.rounding_amount), 0)
+		FROM refunds r
+		WHERE `+refundCondition, args...).Scan(&summary.TotalRefund, &refundedRounding)
 	if err != nil {
 		return nil, err
 	}
+	summary.NetRevenue = summary.TotalRevenue - summary.TotalRefund
+	summary.TotalRounding -= refundedRounding
 
-	// Get produk terlaris dalam rentang tanggal
+	// Produk terlaris dalam periode, quantity bersih setelah dikurangi refund (sama seperti fillProfit)
 	var bestSeller models.BestSeller
 	err = repo.db.QueryRow(`
-		SELECT p.name, COALESCE(SUM(td.quantity), 0) as qty_terjual
-		FROM transaction_details td
-		JOIN transactions t ON td.transaction_id = t.id
-		JOIN products p ON td.product_id = p.id
-		WHERE DATE(t.created_at) >= $1 AND DATE(t.created_at) <= $2
-		GROUP BY p.id, p.name
+		SELECT x.product_name, SUM(x.qty) as qty_terjual
+		FROM (
+			SELECT td.product_id, td.product_name, td.quantity AS qty
+			FROM transaction_details td
+			JOIN transactions t ON td.transaction_id = t.id
+			WHERE `+salesCondition+`
+			UNION ALL
+			SELECT td.product_id, td.product_name, -ri.quantity
+			FROM refund_items ri
+			JOIN refunds r ON ri.refund_id = r.id
+			JOIN transaction_details td ON ri.transaction_detail_id = td.id
+			WHERE `+refundCondition+`
+		) x
+		GROUP BY x.product_id, x.product_name
+		HAVING SUM(x.qty) > 0
 		ORDER BY qty_terjual DESC
-		LIMIT 1
-	`, startDate, endDate).Scan(&bestSeller.Nama, &bestSeller.QtyTerjual)
-	
+		LIMIT 1`, args...).Scan(&bestSeller.Nama, &bestSeller.QtyTerjual)
+
 	if err == sql.ErrNoRows {
 		summary.ProdukTerlaris = nil
 	} else if err != nil {
@@ -152,5 +1358,191 @@ func (repo *TransactionRepository) GetSalesSummaryByDateRange(startDate, endDate
 		summary.ProdukTerlaris = &bestSeller
 	}
 
+	summary.PerMetode, err = repo.getPaymentBreakdown(salesCondition, refundCondition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.fillTaxes(summary, salesCondition, refundCondition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.fillProfit(summary, salesCondition, refundCondition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if outletID == 0 {
+		summary.PerOutlet, err = repo.getOutletBreakdown(salesCondition, refundCondition, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return summary, nil
+}
+
+// getOutletBreakdown - omzet, pembulatan tunai, refund dan jumlah transaksi per outlet untuk laporan gabungan.
+// Outlet yang diarsipkan hanya muncul kalau punya penjualan atau refund di periode itu.
+func (repo *TransactionRepository) getOutletBreakdown(salesCondition, refundCondition string, args ...interface{}) ([]models.OutletSalesSummary, error) {
+	rows, err := repo.db.Query(`
+		SELECT o.id, o.name, COALESCE(s.revenue, 0), COALESCE(s.rounding, 0) - COALESCE(rf.rounding, 0), COALESCE(s.transactions, 0), COALESCE(rf.amount, 0)
+		FROM outlets o
+		LEFT JOIN (
+			SELECT t.outlet_id, SUM(t.total_amount) AS revenue, SUM(t.rounding_amount) AS rounding, COUNT(*) AS transactions
+			FROM transactions t
+			WHERE `+salesCondition+`
+			GROUP BY t.outlet_id
+		) s ON s.outlet_id = o.id
+		LEFT JOIN (
+			SELECT t.outlet_id, SUM(r.amount) AS amount, SUM(r.rounding_amount) AS rounding
+			FROM refunds r
+			JOIN transactions t ON r.transaction_id = t.id
+			WHERE `+refundCondition+`
+			GROUP BY t.outlet_id
+		) rf ON rf.outlet_id = o.id
+		WHERE o.archived_at IS NULL OR s.outlet_id IS NOT NULL OR rf.outlet_id IS NOT NULL
+		ORDER BY o.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breakdown := make([]models.OutletSalesSummary, 0)
+	for rows.Next() {
+		var o models.OutletSalesSummary
+		if err := rows.Scan(&o.OutletID, &o.OutletName, &o.TotalRevenue, &o.TotalRounding, &o.TotalTransaksi, &o.TotalRefund); err != nil {
+			return nil, err
+		}
+		o.NetRevenue = o.TotalRevenue - o.TotalRefund
+		breakdown = append(breakdown, o)
+	}
+
+	return breakdown, rows.Err()
+}
+
+// fillTaxes - DPP dan pajak per kategori pajak serta total service charge dari snapshot di detail transaksi,
+// dikurangi pajak dan service charge yang ikut di-refund dalam periode (sama seperti TotalRefund)
+func (repo *TransactionRepository) fillTaxes(summary *models.SalesSummary, salesCondition, refundCondition string, args ...interface{}) error {
+	rows, err := repo.db.Query(`
+		SELECT x.tax_category, x.tax_rate, SUM(x.base), SUM(x.tax), SUM(x.service_charge)
+		FROM (
+			SELECT COALESCE(td.tax_category, '') AS tax_category, td.tax_rate, td.tax_base_amount AS base, td.tax_amount AS tax,
+				td.service_charge_amount AS service_charge
+			FROM transaction_details td
+			JOIN transactions t ON td.transaction_id = t.id
+			WHERE `+salesCondition+`
+			UNION ALL
+			SELECT COALESCE(td.tax_category, ''), td.tax_rate, -(ri.amount - ri.tax_amount), -ri.tax_amount, -ri.service_charge_amount
+			FROM refund_items ri
+			JOIN refunds r ON ri.refund_id = r.id
+			JOIN transaction_details td ON ri.transaction_detail_id = td.id
+			WHERE `+refundCondition+`
+		) x
+		GROUP BY x.tax_category, x.tax_rate
+		ORDER BY x.tax_category, x.tax_rate`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	summary.Taxes = make([]models.TaxSummary, 0)
+	for rows.Next() {
+		var ts models.TaxSummary
+		var serviceCharge int
+		if err := rows.Scan(&ts.TaxCategory, &ts.TaxRate, &ts.TaxBaseAmount, &ts.TaxAmount, &serviceCharge); err != nil {
+			return err
+		}
+		summary.Taxes = append(summary.Taxes, ts)
+		summary.TotalTax += ts.TaxAmount
+		summary.TotalServiceCharge += serviceCharge
+	}
+
+	return rows.Err()
+}
+
+// fillProfit - hitung HPP dan laba kotor (total & per produk) dari snapshot unit_cost dan nama produk.
+// Omzet produk tidak termasuk pajak, jadi laba kotor dihitung dari NetRevenue - TotalTax (fillTaxes dipanggil dulu).
+// Penjualan difilter dengan salesCondition (alias t), refund dengan refundCondition (alias r)
+// supaya refund mengurangi periode tempat refund terjadi, sama seperti TotalRefund.
+func (repo *TransactionRepository) fillProfit(summary *models.SalesSummary, salesCondition, refundCondition string, args ...interface{}) error {
+	rows, err := repo.db.Query(`
+		SELECT COALESCE(x.product_id, 0), x.product_name, SUM(x.qty), SUM(x.revenue), ROUND(SUM(x.cogs))
+		FROM (
+			SELECT td.product_id, td.product_name, td.quantity AS qty, td.total_amount - td.tax_amount AS revenue, td.unit_cost * td.quantity AS cogs
+			FROM transaction_details td
+			JOIN transactions t ON td.transaction_id = t.id
+			WHERE `+salesCondition+`
+			UNION ALL
+			SELECT td.product_id, td.product_name, -ri.quantity, -(ri.amount - ri.tax_amount), -(td.unit_cost * ri.quantity)
+			FROM refund_items ri
+			JOIN refunds r ON ri.refund_id = r.id
+			JOIN transaction_details td ON ri.transaction_detail_id = td.id
+			WHERE `+refundCondition+`
+		) x
+		GROUP BY x.product_id, x.product_name
+		ORDER BY SUM(x.revenue) DESC`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	summary.ProfitProduk = make([]models.ProductProfit, 0)
+	for rows.Next() {
+		var pp models.ProductProfit
+		if err := rows.Scan(&pp.ProductID, &pp.Nama, &pp.QtyTerjual, &pp.Revenue, &pp.COGS); err != nil {
+			return err
+		}
+		pp.GrossProfit = pp.Revenue - pp.COGS
+		pp.MarginPersen = models.MarginPercentage(pp.GrossProfit, pp.Revenue)
+		summary.ProfitProduk = append(summary.ProfitProduk, pp)
+
+		summary.COGS += pp.COGS
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	summary.GrossProfit = summary.NetRevenue - summary.TotalTax - summary.COGS
+	summary.MarginPersen = models.MarginPercentage(summary.GrossProfit, summary.NetRevenue-summary.TotalTax)
+	return nil
+}
+
+// getPaymentBreakdown - omzet, refund dan jumlah transaksi per metode pembayaran untuk laporan.
+// Refund dihitung berdasarkan tanggal refund (refundCondition), sama seperti TotalRefund.
+func (repo *TransactionRepository) getPaymentBreakdown(salesCondition, refundCondition string, args ...interface{}) ([]models.PaymentMethodSummary, error) {
+	rows, err := repo.db.Query(`
+		SELECT m.method, SUM(m.revenue)::bigint, SUM(m.refund)::bigint, SUM(m.transactions)::bigint
+		FROM (
+			SELECT tp.method, SUM(tp.amount) AS revenue, 0 AS refund, COUNT(DISTINCT tp.transaction_id) AS transactions
+			FROM transaction_payments tp
+			JOIN transactions t ON tp.transaction_id = t.id
+			WHERE `+salesCondition+`
+			GROUP BY tp.method
+			UNION ALL
+			SELECT rp.method, 0, SUM(rp.amount), 0
+			FROM refund_payments rp
+			JOIN refunds r ON rp.refund_id = r.id
+			WHERE `+refundCondition+`
+			GROUP BY rp.method
+		) m
+		GROUP BY m.method
+		ORDER BY m.method`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breakdown := make([]models.PaymentMethodSummary, 0)
+	for rows.Next() {
+		var m models.PaymentMethodSummary
+		if err := rows.Scan(&m.Metode, &m.TotalRevenue, &m.TotalRefund, &m.TotalTransaksi); err != nil {
+			return nil, err
+		}
+		m.NetRevenue = m.TotalRevenue - m.TotalRefund
+		breakdown = append(breakdown, m)
+	}
+
+	return breakdown, rows.Err()
 }
\ No newline at end of file
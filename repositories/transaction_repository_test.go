@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"kasir-api/database"
+	"kasir-api/models"
+)
+
+// testDB - koneksi ke database test dari TEST_DB_CONN, schema dari README harus sudah dijalankan.
+// Test menulis data sungguhan, jadi pakai database khusus test. Tanpa TEST_DB_CONN test di-skip.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn := os.Getenv("TEST_DB_CONN")
+	if conn == "" {
+		t.Skip("TEST_DB_CONN is not set")
+	}
+	db, err := database.InitDB(conn)
+	if err != nil {
+		t.Fatalf("connect test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// createTestProduct - produk pcs tanpa pajak dengan stok awal di outlet default, dicatat lewat stock ledger
+func createTestProduct(t *testing.T, db *sql.DB, price int, stock float64) (productID, outletID int) {
+	t.Helper()
+	name := fmt.Sprintf("Test Product %d", time.Now().UnixNano())
+	err := db.QueryRow("INSERT INTO products (name, price, stock) VALUES ($1, $2, 0) RETURNING id", name, price).Scan(&productID)
+	if err != nil {
+		t.Fatalf("insert product: %v", err)
+	}
+
+	movement, err := NewStockRepository(db).Adjust(productID, models.StockAdjustmentRequest{
+		Type:     models.StockMovementReceipt,
+		Quantity: stock,
+		Reason:   "test fixture",
+	})
+	if err != nil {
+		t.Fatalf("add initial stock: %v", err)
+	}
+	return productID, movement.OutletID
+}
+
+func testCheckoutRequest(productID, outletID int, quantity float64, cash int) models.CheckoutRequest {
+	return models.CheckoutRequest{
+		OutletID: outletID,
+		Items:    []models.CheckoutItem{{ProductID: productID, Quantity: quantity}},
+		Payments: []models.PaymentInput{{Method: models.PaymentMethodCash, Amount: cash}},
+		Tax:      models.TaxConfig{Rates: models.TaxRates{models.DefaultTaxCategory: 0}, Rounding: models.RoundingNearest},
+	}
+}
+
+func outletStock(t *testing.T, db *sql.DB, productID, outletID int) float64 {
+	t.Helper()
+	var stock float64
+	err := db.QueryRow("SELECT COALESCE((SELECT stock FROM outlet_stocks WHERE product_id = $1 AND outlet_id = $2), 0)",
+		productID, outletID).Scan(&stock)
+	if err != nil {
+		t.Fatalf("read outlet stock: %v", err)
+	}
+	return stock
+}
+
+func TestCreateTransactionInsufficientStock(t *testing.T) {
+	db := testDB(t)
+	repo := NewTransactionRepository(db)
+	productID, outletID := createTestProduct(t, db, 5000, 2)
+
+	_, err := repo.CreateTransaction(testCheckoutRequest(productID, outletID, 3, 15000), nil)
+	var stockErr *models.InsufficientStockError
+	if !errors.As(err, &stockErr) {
+		t.Fatalf("CreateTransaction() error = %v, want InsufficientStockError", err)
+	}
+	want := models.StockShortage{ProductID: productID, Requested: 3, Available: 2}
+	if len(stockErr.Items) != 1 || stockErr.Items[0] != want {
+		t.Fatalf("shortages = %+v, want [%+v]", stockErr.Items, want)
+	}
+	if got := outletStock(t, db, productID, outletID); got != 2 {
+		t.Fatalf("outlet stock after rejected checkout = %g, want 2", got)
+	}
+
+	// Stok yang pas masih boleh habis terjual
+	if _, err := repo.CreateTransaction(testCheckoutRequest(productID, outletID, 2, 10000), nil); err != nil {
+		t.Fatalf("CreateTransaction() for the remaining stock error = %v", err)
+	}
+	if got := outletStock(t, db, productID, outletID); got != 0 {
+		t.Fatalf("outlet stock after selling out = %g, want 0", got)
+	}
+}
+
+func TestCreateTransactionIdempotentReplay(t *testing.T) {
+	db := testDB(t)
+	repo := NewTransactionRepository(db)
+	productID, outletID := createTestProduct(t, db, 5000, 10)
+	req := testCheckoutRequest(productID, outletID, 1, 5000)
+
+	key := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	newKey := func(hash string) *models.IdempotencyKey {
+		return &models.IdempotencyKey{Key: key, RequestHash: hash, ExpiresAt: time.Now().Add(time.Hour)}
+	}
+
+	// Key yang belum pernah dipakai tidak punya transaksi untuk di-replay
+	found, err := repo.FindIdempotentTransaction(newKey("hash-a"))
+	if err != nil || found != nil {
+		t.Fatalf("FindIdempotentTransaction() before checkout = %v, %v, want nil, nil", found, err)
+	}
+
+	first, err := repo.CreateTransaction(req, newKey("hash-a"))
+	if err != nil {
+		t.Fatalf("first CreateTransaction() error = %v", err)
+	}
+
+	retry := newKey("hash-a")
+	replayed, err := repo.CreateTransaction(req, retry)
+	if err != nil {
+		t.Fatalf("retried CreateTransaction() error = %v", err)
+	}
+	if !retry.Replayed || replayed.ID != first.ID {
+		t.Fatalf("retry = transaction %d (replayed %v), want replay of %d", replayed.ID, retry.Replayed, first.ID)
+	}
+
+	lookup := newKey("hash-a")
+	found, err = repo.FindIdempotentTransaction(lookup)
+	if err != nil || found == nil || found.ID != first.ID || !lookup.Replayed {
+		t.Fatalf("FindIdempotentTransaction() = %v, %v, want replay of %d", found, err, first.ID)
+	}
+
+	if _, err := repo.FindIdempotentTransaction(newKey("hash-b")); !errors.Is(err, models.ErrIdempotencyKeyReused) {
+		t.Fatalf("FindIdempotentTransaction() with another body error = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if _, err := repo.CreateTransaction(req, newKey("hash-b")); !errors.Is(err, models.ErrIdempotencyKeyReused) {
+		t.Fatalf("CreateTransaction() with another body error = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	// Replay tidak memotong stok lagi
+	if got := outletStock(t, db, productID, outletID); got != 9 {
+		t.Fatalf("outlet stock after replays = %g, want 9", got)
+	}
+}
+
+func TestRefundTransactionLimits(t *testing.T) {
+	db := testDB(t)
+	repo := NewTransactionRepository(db)
+	productID, outletID := createTestProduct(t, db, 5000, 5)
+
+	transaction, err := repo.CreateTransaction(testCheckoutRequest(productID, outletID, 2, 10000), nil)
+	if err != nil {
+		t.Fatalf("CreateTransaction() error = %v", err)
+	}
+	detailID := transaction.Details[0].ID
+	refundItems := func(quantity float64) models.RefundRequest {
+		return models.RefundRequest{Reason: "Barang rusak", Items: []models.RefundRequestItem{{TransactionDetailID: detailID, Quantity: quantity}}}
+	}
+
+	refund, err := repo.RefundTransaction(transaction.ID, refundItems(1))
+	if err != nil {
+		t.Fatalf("first RefundTransaction() error = %v", err)
+	}
+	if want := transaction.Details[0].TotalAmount / 2; refund.Amount != want {
+		t.Fatalf("first refund amount = %d, want %d", refund.Amount, want)
+	}
+
+	if _, err := repo.RefundTransaction(transaction.ID, refundItems(2)); !errors.Is(err, models.ErrRefundNotAllowed) {
+		t.Fatalf("RefundTransaction() above the remaining quantity error = %v, want ErrRefundNotAllowed", err)
+	}
+	other := models.RefundRequest{Reason: "Barang rusak", Items: []models.RefundRequestItem{{TransactionDetailID: detailID + 1000000, Quantity: 1}}}
+	if _, err := repo.RefundTransaction(transaction.ID, other); !errors.Is(err, models.ErrRefundNotAllowed) {
+		t.Fatalf("RefundTransaction() for a line of another transaction error = %v, want ErrRefundNotAllowed", err)
+	}
+
+	if _, err := repo.RefundTransaction(transaction.ID, refundItems(1)); err != nil {
+		t.Fatalf("last RefundTransaction() error = %v", err)
+	}
+	if _, err := repo.VoidTransaction(transaction.ID, "Salah input kasir"); !errors.Is(err, models.ErrRefundNotAllowed) {
+		t.Fatalf("VoidTransaction() after a full refund error = %v, want ErrRefundNotAllowed", err)
+	}
+	if _, err := repo.RefundTransaction(0, refundItems(1)); !errors.Is(err, models.ErrTransactionNotFound) {
+		t.Fatalf("RefundTransaction() for an unknown transaction error = %v, want ErrTransactionNotFound", err)
+	}
+
+	// Semua stok yang terjual sudah kembali ke outlet
+	if got := outletStock(t, db, productID, outletID); got != 5 {
+		t.Fatalf("outlet stock after refunds = %g, want 5", got)
+	}
+}
@@ -0,0 +1,233 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"kasir-api/models"
+)
+
+type VoucherRepository struct {
+	db *sql.DB
+}
+
+func NewVoucherRepository(db *sql.DB) *VoucherRepository {
+	return &VoucherRepository{db: db}
+}
+
+// voucherColumns - used_count hanya menghitung penukaran yang tidak dibatalkan (void/refund penuh)
+const voucherColumns = `v.id, v.code, COALESCE(v.description, ''), v.type, v.value, v.max_discount, v.min_spend,
+	TO_CHAR(v.start_date, 'YYYY-MM-DD'), TO_CHAR(v.end_date, 'YYYY-MM-DD'), v.usage_limit, v.per_customer_limit,
+	(SELECT COUNT(*) FROM voucher_redemptions vr WHERE vr.voucher_id = v.id AND vr.released_at IS NULL),
+	v.created_at, v.archived_at`
+
+// scanVouchers - baca semua row hasil query voucherColumns lalu tutup rows
+func scanVouchers(rows *sql.Rows) ([]models.Voucher, error) {
+	defer rows.Close()
+
+	vouchers := make([]models.Voucher, 0)
+	for rows.Next() {
+		var v models.Voucher
+		err := rows.Scan(&v.ID, &v.Code, &v.Description, &v.Type, &v.Value, &v.MaxDiscount, &v.MinSpend, &v.StartDate, &v.EndDate,
+			&v.UsageLimit, &v.PerCustomerLimit, &v.UsedCount, &v.CreatedAt, &v.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+		vouchers = append(vouchers, v)
+	}
+
+	return vouchers, rows.Err()
+}
+
+// GetAll - voucher yang diarsipkan hanya ikut kalau includeArchived true
+func (repo *VoucherRepository) GetAll(includeArchived bool) ([]models.Voucher, error) {
+	query := "SELECT " + voucherColumns + " FROM vouchers v"
+	if !includeArchived {
+		query += " WHERE v.archived_at IS NULL"
+	}
+	query += " ORDER BY v.code"
+	rows, err := repo.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanVouchers(rows)
+}
+
+// GetByID - ambil voucher by ID, termasuk yang diarsipkan
+func (repo *VoucherRepository) GetByID(id int) (*models.Voucher, error) {
+	rows, err := repo.db.Query("SELECT "+voucherColumns+" FROM vouchers v WHERE v.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	vouchers, err := scanVouchers(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(vouchers) == 0 {
+		return nil, models.ErrVoucherNotFound
+	}
+
+	return &vouchers[0], nil
+}
+
+func (repo *VoucherRepository) Create(voucher *models.Voucher) error {
+	err := repo.db.QueryRow(`INSERT INTO vouchers (code, description, type, value, max_discount, min_spend, start_date, end_date,
+			usage_limit, per_customer_limit)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at`,
+		voucher.Code, nullIfEmpty(voucher.Description), voucher.Type, voucher.Value, voucher.MaxDiscount, voucher.MinSpend,
+		voucher.StartDate, voucher.EndDate, voucher.UsageLimit, voucher.PerCustomerLimit).Scan(&voucher.ID, &voucher.CreatedAt)
+	return mapVoucherUniqueViolation(err)
+}
+
+// Update - ubah aturan voucher, penukaran yang sudah ada tetap dihitung terhadap kuota baru
+func (repo *VoucherRepository) Update(voucher *models.Voucher) error {
+	err := repo.db.QueryRow(`UPDATE vouchers SET code = $1, description = $2, type = $3, value = $4, max_discount = $5, min_spend = $6,
+			start_date = $7, end_date = $8, usage_limit = $9, per_customer_limit = $10
+		WHERE id = $11 RETURNING created_at, archived_at,
+			(SELECT COUNT(*) FROM voucher_redemptions vr WHERE vr.voucher_id = $11 AND vr.released_at IS NULL)`,
+		voucher.Code, nullIfEmpty(voucher.Description), voucher.Type, voucher.Value, voucher.MaxDiscount, voucher.MinSpend,
+		voucher.StartDate, voucher.EndDate, voucher.UsageLimit, voucher.PerCustomerLimit, voucher.ID).
+		Scan(&voucher.CreatedAt, &voucher.ArchivedAt, &voucher.UsedCount)
+	if err == sql.ErrNoRows {
+		return models.ErrVoucherNotFound
+	}
+	return mapVoucherUniqueViolation(err)
+}
+
+// Delete - arsipkan voucher (soft delete), kode tidak bisa ditukarkan lagi
+func (repo *VoucherRepository) Delete(id int) error {
+	result, err := repo.db.Exec("UPDATE vouchers SET archived_at = COALESCE(archived_at, NOW()) WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrVoucherNotFound
+	}
+
+	return nil
+}
+
+// Restore - aktifkan kembali voucher yang diarsipkan
+func (repo *VoucherRepository) Restore(id int) error {
+	result, err := repo.db.Exec("UPDATE vouchers SET archived_at = NULL WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrVoucherNotFound
+	}
+
+	return nil
+}
+
+// GetUsageReport - penukaran voucher per voucher berdasarkan tanggal transaksi, outletID 0 berarti semua outlet
+func (repo *VoucherRepository) GetUsageReport(startDate, endDate string, outletID int) (*models.VoucherUsageReport, error) {
+	report := &models.VoucherUsageReport{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Vouchers:  make([]models.VoucherUsage, 0),
+	}
+
+	condition := "vr.released_at IS NULL AND DATE(t.created_at) >= $1 AND DATE(t.created_at) <= $2"
+	args := []interface{}{startDate, endDate}
+	if outletID != 0 {
+		condition += " AND t.outlet_id = $3"
+		args = append(args, outletID)
+		report.OutletID = &outletID
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT v.id, v.code, COUNT(*), COUNT(DISTINCT vr.customer_ref), COALESCE(SUM(vr.amount), 0), COALESCE(SUM(t.total_amount), 0)
+		FROM voucher_redemptions vr
+		JOIN vouchers v ON v.id = vr.voucher_id
+		JOIN transactions t ON t.id = vr.transaction_id
+		WHERE `+condition+`
+		GROUP BY v.id, v.code
+		ORDER BY COUNT(*) DESC, v.code`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u models.VoucherUsage
+		if err := rows.Scan(&u.VoucherID, &u.Code, &u.Redemptions, &u.UniqueCustomers, &u.DiscountAmount, &u.Sales); err != nil {
+			return nil, err
+		}
+		report.TotalRedemptions += u.Redemptions
+		report.TotalDiscount += u.DiscountAmount
+		report.Vouchers = append(report.Vouchers, u)
+	}
+
+	return report, rows.Err()
+}
+
+// lockVoucher - lock row voucher lalu cek kuota total dan kuota pelanggan. Checkout paralel dengan
+// voucher yang sama menunggu di lock ini sampai checkout lain commit, jadi kuota tidak bisa terlewati.
+// Penukaran dihitung di statement terpisah setelah lock supaya penukaran yang baru di-commit ikut terhitung.
+func lockVoucher(tx *sql.Tx, code, customerRef string) (*models.Voucher, error) {
+	var id int
+	err := tx.QueryRow("SELECT id FROM vouchers WHERE code = $1 FOR UPDATE", code).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrVoucherNotFound, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query("SELECT "+voucherColumns+" FROM vouchers v WHERE v.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	vouchers, err := scanVouchers(rows)
+	if err != nil {
+		return nil, err
+	}
+	v := vouchers[0]
+
+	if v.UsageLimit > 0 && v.UsedCount >= v.UsageLimit {
+		return nil, fmt.Errorf("%w: %s has been used %d of %d times", models.ErrVoucherLimitReached, v.Code, v.UsedCount, v.UsageLimit)
+	}
+
+	if v.PerCustomerLimit > 0 {
+		if customerRef == "" {
+			return nil, fmt.Errorf("%w: %s", models.ErrCustomerRequired, v.Code)
+		}
+		var used int
+		err := tx.QueryRow("SELECT COUNT(*) FROM voucher_redemptions WHERE voucher_id = $1 AND customer_ref = $2 AND released_at IS NULL",
+			v.ID, customerRef).Scan(&used)
+		if err != nil {
+			return nil, err
+		}
+		if used >= v.PerCustomerLimit {
+			return nil, fmt.Errorf("%w: %s already used %d times by this customer", models.ErrVoucherLimitReached, v.Code, used)
+		}
+	}
+
+	return &v, nil
+}
+
+// mapVoucherUniqueViolation - kode voucher yang sudah dipakai jadi ErrDuplicateVoucher
+func mapVoucherUniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "vouchers_code_key" {
+		return models.ErrDuplicateVoucher
+	}
+	return err
+}
@@ -6,15 +6,16 @@ import (
 )
 
 type CategoryService struct {
-	repo *repositories.CategoryRepository
+	repo        *repositories.CategoryRepository
+	productRepo *repositories.ProductRepository
 }
 
-func NewCategoryService(repo *repositories.CategoryRepository) *CategoryService {
-	return &CategoryService{repo: repo}
+func NewCategoryService(repo *repositories.CategoryRepository, productRepo *repositories.ProductRepository) *CategoryService {
+	return &CategoryService{repo: repo, productRepo: productRepo}
 }
 
-func (s *CategoryService) GetAll() ([]models.Category, error) {
-	return s.repo.GetAll()
+func (s *CategoryService) GetAll(includeArchived bool) ([]models.Category, error) {
+	return s.repo.GetAll(includeArchived)
 }
 
 func (s *CategoryService) Create(data *models.Category) error {
@@ -29,6 +30,23 @@ func (s *CategoryService) Update(category *models.Category) error {
 	return s.repo.Update(category)
 }
 
-func (s *CategoryService) Delete(id int) error {
-	return s.repo.Delete(id)
+// Delete - reassignTo opsional, lihat CategoryRepository.Delete
+func (s *CategoryService) Delete(id int, reassignTo *int) error {
+	return s.repo.Delete(id, reassignTo)
+}
+
+func (s *CategoryService) Restore(id int) (*models.Category, error) {
+	if err := s.repo.Restore(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// GetProducts - produk aktif dalam satu category
+func (s *CategoryService) GetProducts(id int) ([]models.Product, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	products, _, err := s.productRepo.GetAll(models.ProductFilter{CategoryID: id, Sort: "name"})
+	return products, err
 }
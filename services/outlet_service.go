@@ -0,0 +1,41 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type OutletService struct {
+	repo *repositories.OutletRepository
+}
+
+func NewOutletService(repo *repositories.OutletRepository) *OutletService {
+	return &OutletService{repo: repo}
+}
+
+func (s *OutletService) GetAll(includeArchived bool) ([]models.Outlet, error) {
+	return s.repo.GetAll(includeArchived)
+}
+
+func (s *OutletService) Create(data *models.Outlet) error {
+	return s.repo.Create(data)
+}
+
+func (s *OutletService) GetByID(id int) (*models.Outlet, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *OutletService) Update(outlet *models.Outlet) error {
+	return s.repo.Update(outlet)
+}
+
+func (s *OutletService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+func (s *OutletService) Restore(id int) (*models.Outlet, error) {
+	if err := s.repo.Restore(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
@@ -1,27 +1,45 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"kasir-api/models"
 	"kasir-api/repositories"
 )
 
 type ProductService struct {
-	repo *repositories.ProductRepository
+	repo         *repositories.ProductRepository
+	categoryRepo *repositories.CategoryRepository
+	stockRepo    *repositories.StockRepository
+	scaleFormats []models.ScaleBarcodeFormat
+	taxRates     models.TaxRates
 }
 
-func NewProductService(repo *repositories.ProductRepository) *ProductService {
-	return &ProductService{repo: repo}
+func NewProductService(repo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository, stockRepo *repositories.StockRepository, scaleFormats []models.ScaleBarcodeFormat, taxRates models.TaxRates) *ProductService {
+	return &ProductService{repo: repo, categoryRepo: categoryRepo, stockRepo: stockRepo, scaleFormats: scaleFormats, taxRates: taxRates}
 }
 
-func (s *ProductService) GetAll() ([]models.Product, error) {
-	return s.repo.GetAll()
-}
+func (s *ProductService) GetAll(filter models.ProductFilter) (*models.ProductList, error) {
+	products, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *ProductService) SearchByName(name string) ([]models.Product, error) {
-	return s.repo.SearchByName(name)
+	return &models.ProductList{
+		Data:       products,
+		Pagination: models.NewPagination(filter.Page, filter.Limit, total),
+	}, nil
 }
 
 func (s *ProductService) Create(data *models.Product) error {
+	if err := s.validateCategory(data); err != nil {
+		return err
+	}
+	if err := s.validateTaxCategory(data); err != nil {
+		return err
+	}
 	return s.repo.Create(data)
 }
 
@@ -29,10 +47,146 @@ func (s *ProductService) GetByID(id int) (*models.Product, error) {
 	return s.repo.GetByID(id)
 }
 
-func (s *ProductService) Update(product *models.Product) error {
-	return s.repo.Update(product)
+// GetByBarcode - cari barcode yang terdaftar dulu, kalau tidak ada coba decode sebagai
+// label timbangan dan cari produknya lewat PLU.
+func (s *ProductService) GetByBarcode(code string) (*models.BarcodeLookup, error) {
+	product, err := s.repo.GetByBarcode(code)
+	if err == nil {
+		return &models.BarcodeLookup{Product: *product}, nil
+	}
+	if !errors.Is(err, models.ErrProductNotFound) {
+		return nil, err
+	}
+
+	scale, ok := models.DecodeScaleBarcode(code, s.scaleFormats)
+	if !ok {
+		return nil, err
+	}
+
+	product, err = s.repo.GetByPLU(scale.PLU)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := &models.BarcodeLookup{Product: *product}
+	var quantity float64
+	if scale.ValueType == models.ScaleValuePrice {
+		if product.Price <= 0 {
+			return nil, fmt.Errorf("%w: produk %s belum punya harga, label harga tidak bisa dihitung quantity-nya", models.ErrInvalidScaleLabel, product.Name)
+		}
+		quantity = models.RoundQuantity(float64(scale.Price) / float64(product.Price))
+		lookup.Subtotal = &scale.Price
+	} else {
+		quantity = models.RoundQuantity(scale.Weight)
+	}
+	// Label berat 0, harga 0, atau harga yang terlalu kecil untuk harga per unit produk
+	if quantity <= 0 {
+		return nil, fmt.Errorf("%w: %s menghasilkan quantity 0 untuk produk %s", models.ErrInvalidScaleLabel, code, product.Name)
+	}
+	lookup.Quantity = &quantity
+
+	return lookup, nil
+}
+
+// Update - requestedStock adalah stock yang dikirim client (nil kalau tidak dikirim),
+// hanya boleh sama dengan stok sekarang
+func (s *ProductService) Update(product *models.Product, requestedStock *float64) error {
+	if err := s.validateCategory(product); err != nil {
+		return err
+	}
+	if err := s.validateTaxCategory(product); err != nil {
+		return err
+	}
+	return s.repo.Update(product, requestedStock)
 }
 
 func (s *ProductService) Delete(id int) error {
 	return s.repo.Delete(id)
 }
+
+func (s *ProductService) Restore(id int) (*models.Product, error) {
+	if err := s.repo.Restore(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *ProductService) AdjustStock(productID int, req models.StockAdjustmentRequest) (*models.StockMovement, error) {
+	return s.stockRepo.Adjust(productID, req)
+}
+
+func (s *ProductService) GetStockMovements(filter models.StockMovementFilter) (*models.StockMovementList, error) {
+	movements, total, err := s.stockRepo.GetMovements(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StockMovementList{
+		Data:       movements,
+		Pagination: models.NewPagination(filter.Page, filter.Limit, total),
+	}, nil
+}
+
+func (s *ProductService) GetBatches(productID, outletID int, includeEmpty bool) ([]models.ProductBatch, error) {
+	return s.stockRepo.GetBatches(productID, outletID, includeEmpty)
+}
+
+// GetExpiringBatches - batch yang kadaluarsa dalam days hari (termasuk yang sudah lewat) beserta nilai stoknya
+func (s *ProductService) GetExpiringBatches(days, outletID int) (*models.ExpiringBatchReport, error) {
+	items, err := s.stockRepo.GetExpiringBatches(days, outletID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ExpiringBatchReport{Days: days, Items: items}
+	if outletID != 0 {
+		report.OutletID = &outletID
+	}
+	for _, item := range items {
+		report.TotalValue += item.Value
+	}
+	return report, nil
+}
+
+// GetReorderReport - days adalah periode kecepatan penjualan, coverDays berapa hari stok yang ingin dipegang.
+// outletID 0 berarti stok dan penjualan semua outlet digabung.
+func (s *ProductService) GetReorderReport(days, coverDays, outletID int) (*models.ReorderReport, error) {
+	candidates, err := s.repo.GetReorderCandidates(days, outletID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.NewReorderReport(candidates, days, coverDays)
+	if outletID != 0 {
+		report.OutletID = &outletID
+	}
+	return report, nil
+}
+
+// validateCategory - pastikan category_id (kalau diisi) menunjuk ke category yang ada dan aktif
+func (s *ProductService) validateCategory(product *models.Product) error {
+	if product.CategoryID == nil {
+		return nil
+	}
+	category, err := s.categoryRepo.GetByID(*product.CategoryID)
+	if err != nil {
+		return err
+	}
+	if category.ArchivedAt != nil {
+		return models.ErrCategoryArchived
+	}
+	product.Category = category
+	return nil
+}
+
+// validateTaxCategory - tax_category harus ada di konfigurasi TAX_RATES, kosong berarti DefaultTaxCategory
+func (s *ProductService) validateTaxCategory(product *models.Product) error {
+	product.TaxCategory = strings.TrimSpace(product.TaxCategory)
+	if product.TaxCategory == "" {
+		product.TaxCategory = models.DefaultTaxCategory
+	}
+	if _, ok := s.taxRates[product.TaxCategory]; !ok {
+		return fmt.Errorf("%w: %s (configured: %s)", models.ErrUnknownTaxCategory, product.TaxCategory, strings.Join(s.taxRates.Categories(), ", "))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type PromotionService struct {
+	repo *repositories.PromotionRepository
+}
+
+func NewPromotionService(repo *repositories.PromotionRepository) *PromotionService {
+	return &PromotionService{repo: repo}
+}
+
+func (s *PromotionService) GetAll(includeArchived bool) ([]models.Promotion, error) {
+	return s.repo.GetAll(includeArchived)
+}
+
+func (s *PromotionService) Create(data *models.Promotion) error {
+	return s.repo.Create(data)
+}
+
+func (s *PromotionService) GetByID(id int) (*models.Promotion, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *PromotionService) Update(supplier *models.Promotion) error {
+	return s.repo.Update(supplier)
+}
+
+func (s *PromotionService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+func (s *PromotionService) Restore(id int) (*models.Promotion, error) {
+	if err := s.repo.Restore(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type PurchaseOrderService struct {
+	repo         *repositories.PurchaseOrderRepository
+	supplierRepo *repositories.SupplierRepository
+}
+
+func NewPurchaseOrderService(repo *repositories.PurchaseOrderRepository, supplierRepo *repositories.SupplierRepository) *PurchaseOrderService {
+	return &PurchaseOrderService{repo: repo, supplierRepo: supplierRepo}
+}
+
+func (s *PurchaseOrderService) Create(req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	if err := s.validateSupplier(req.SupplierID); err != nil {
+		return nil, err
+	}
+	id, err := s.repo.Create(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *PurchaseOrderService) Update(id int, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	if err := s.validateSupplier(req.SupplierID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(id, req); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *PurchaseOrderService) List(filter models.PurchaseOrderFilter) (*models.PurchaseOrderList, error) {
+	orders, total, err := s.repo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PurchaseOrderList{
+		Data:       orders,
+		Pagination: models.NewPagination(filter.Page, filter.Limit, total),
+	}, nil
+}
+
+func (s *PurchaseOrderService) GetByID(id int) (*models.PurchaseOrder, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *PurchaseOrderService) MarkOrdered(id int) (*models.PurchaseOrder, error) {
+	if err := s.repo.MarkOrdered(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *PurchaseOrderService) Cancel(id int) (*models.PurchaseOrder, error) {
+	if err := s.repo.Cancel(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *PurchaseOrderService) Receive(id int, req models.GoodsReceiptRequest) (*models.GoodsReceipt, error) {
+	return s.repo.Receive(id, req)
+}
+
+// validateSupplier - PO hanya boleh ke supplier yang ada dan aktif
+func (s *PurchaseOrderService) validateSupplier(supplierID int) error {
+	supplier, err := s.supplierRepo.GetByID(supplierID)
+	if err != nil {
+		return err
+	}
+	if supplier.ArchivedAt != nil {
+		return models.ErrSupplierArchived
+	}
+	return nil
+}
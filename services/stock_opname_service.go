@@ -0,0 +1,62 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type StockOpnameService struct {
+	repo         *repositories.StockOpnameRepository
+	categoryRepo *repositories.CategoryRepository
+}
+
+func NewStockOpnameService(repo *repositories.StockOpnameRepository, categoryRepo *repositories.CategoryRepository) *StockOpnameService {
+	return &StockOpnameService{repo: repo, categoryRepo: categoryRepo}
+}
+
+func (s *StockOpnameService) Create(req models.CreateStockOpnameRequest) (*models.StockOpname, error) {
+	if req.CategoryID != nil {
+		if _, err := s.categoryRepo.GetByID(*req.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.Create(req)
+}
+
+func (s *StockOpnameService) List(filter models.StockOpnameFilter) (*models.StockOpnameList, error) {
+	opnames, total, err := s.repo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StockOpnameList{
+		Data:       opnames,
+		Pagination: models.NewPagination(filter.Page, filter.Limit, total),
+	}, nil
+}
+
+func (s *StockOpnameService) GetByID(id int) (*models.StockOpname, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *StockOpnameService) AddCounts(id int, req models.StockOpnameCountRequest) (*models.StockOpname, error) {
+	if err := s.repo.AddCounts(id, req); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// Post - sesi yang sudah di-post dikembalikan sebagai laporan selisihnya
+func (s *StockOpnameService) Post(id int) (*models.StockOpname, error) {
+	if err := s.repo.Post(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *StockOpnameService) Cancel(id int) (*models.StockOpname, error) {
+	if err := s.repo.Cancel(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
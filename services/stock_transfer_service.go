@@ -0,0 +1,63 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type StockTransferService struct {
+	repo *repositories.StockTransferRepository
+}
+
+func NewStockTransferService(repo *repositories.StockTransferRepository) *StockTransferService {
+	return &StockTransferService{repo: repo}
+}
+
+func (s *StockTransferService) Create(req models.StockTransferRequest) (*models.StockTransfer, error) {
+	if req.SourceOutletID == req.DestinationOutletID {
+		return nil, models.ErrSameOutletTransfer
+	}
+	id, err := s.repo.Create(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *StockTransferService) List(filter models.StockTransferFilter) (*models.StockTransferList, error) {
+	transfers, total, err := s.repo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StockTransferList{
+		Data:       transfers,
+		Pagination: models.NewPagination(filter.Page, filter.Limit, total),
+	}, nil
+}
+
+func (s *StockTransferService) GetByID(id int) (*models.StockTransfer, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *StockTransferService) Dispatch(id int) (*models.StockTransfer, error) {
+	if err := s.repo.Dispatch(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// Receive - transfer yang sudah diterima dikembalikan beserta selisih per item
+func (s *StockTransferService) Receive(id int, req models.StockTransferReceiveRequest) (*models.StockTransfer, error) {
+	if err := s.repo.Receive(id, req); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *StockTransferService) Cancel(id int) (*models.StockTransfer, error) {
+	if err := s.repo.Cancel(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
@@ -1,26 +1,153 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
 	"kasir-api/models"
 	"kasir-api/repositories"
 )
 
 type TransactionService struct {
-	repo *repositories.TransactionRepository
+	repo           *repositories.TransactionRepository
+	productService *ProductService
+	idempotencyTTL time.Duration
+	discountLimits models.DiscountLimits
+	tax            models.TaxConfig
+	cashRounding   models.CashRounding
+}
+
+func NewTransactionService(repo *repositories.TransactionRepository, productService *ProductService, idempotencyTTL time.Duration, discountLimits models.DiscountLimits, tax models.TaxConfig, cashRounding models.CashRounding) *TransactionService {
+	return &TransactionService{repo: repo, productService: productService, idempotencyTTL: idempotencyTTL, discountLimits: discountLimits, tax: tax, cashRounding: cashRounding}
+}
+
+// PriceCart - dry-run checkout: harga, promo, diskon, voucher dan pajak dihitung sama persis tanpa membuat transaksi atau menukarkan voucher
+func (s *TransactionService) PriceCart(req models.CheckoutRequest) (*models.CartPrice, error) {
+	var err error
+	req.Items, err = s.resolveBarcodes(req.Items)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.resolveDiscountLimit(&req); err != nil {
+		return nil, err
+	}
+	req.Tax = s.tax
+	req.CashRounding = s.cashRounding
+	return s.repo.PriceCart(req)
+}
+
+// resolveDiscountLimit - role menentukan batas total diskon manual, dicek di repository setelah harga produk diketahui
+func (s *TransactionService) resolveDiscountLimit(req *models.CheckoutRequest) error {
+	role := req.Role
+	if role == "" {
+		role = models.DefaultDiscountRole
+	}
+	maxPercent, ok := s.discountLimits[role]
+	if !ok {
+		return fmt.Errorf("%w: %s", models.ErrUnknownDiscountRole, role)
+	}
+	req.MaxDiscountPercent = maxPercent
+	return nil
+}
+
+// Checkout - idempotencyKey boleh kosong. Kalau diisi, request yang sama dengan key yang sama
+// dalam window idempotencyTTL akan mengembalikan transaksi yang sudah dibuat (replayed = true).
+func (s *TransactionService) Checkout(req models.CheckoutRequest, idempotencyKey string) (transaction *models.Transaction, replayed bool, err error) {
+	var idem *models.IdempotencyKey
+	if idempotencyKey != "" {
+		// Hash dihitung dari request asli, sebelum barcode di-resolve
+		body, err := json.Marshal(req)
+		if err != nil {
+			return nil, false, err
+		}
+		hash := sha256.Sum256(body)
+
+		idem = &models.IdempotencyKey{
+			Key:         idempotencyKey,
+			RequestHash: hex.EncodeToString(hash[:]),
+			ExpiresAt:   time.Now().Add(s.idempotencyTTL),
+		}
+
+		// Retry dengan key yang sudah selesai langsung di-replay, tanpa resolve barcode, role dan harga lagi
+		transaction, err = s.repo.FindIdempotentTransaction(idem)
+		if err != nil || transaction != nil {
+			return transaction, idem.Replayed, err
+		}
+	}
+
+	req.Items, err = s.resolveBarcodes(req.Items)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.resolveDiscountLimit(&req); err != nil {
+		return nil, false, err
+	}
+	req.Tax = s.tax
+	req.CashRounding = s.cashRounding
+
+	transaction, err = s.repo.CreateTransaction(req, idem)
+	return transaction, idem != nil && idem.Replayed, err
+}
+
+// resolveBarcodes - ubah item yang direferensikan lewat barcode jadi product_id.
+// Label timbangan mengisi quantity dari berat (dikali jumlah label) dan, untuk label harga, subtotal tetap.
+func (s *TransactionService) resolveBarcodes(items []models.CheckoutItem) ([]models.CheckoutItem, error) {
+	resolved := make([]models.CheckoutItem, len(items))
+	for i, item := range items {
+		if item.ProductID == 0 && item.Barcode != "" {
+			lookup, err := s.productService.GetByBarcode(item.Barcode)
+			if err != nil {
+				return nil, fmt.Errorf("barcode %s: %w", item.Barcode, err)
+			}
+
+			item.ProductID = lookup.ID
+			if lookup.Quantity != nil {
+				labels := item.Quantity
+				item.Quantity = models.RoundQuantity(*lookup.Quantity * labels)
+				if lookup.Subtotal != nil {
+					subtotal := int(math.Round(float64(*lookup.Subtotal) * labels))
+					item.FixedSubtotal = &subtotal
+				}
+			}
+		}
+		resolved[i] = item
+	}
+	return resolved, nil
+}
+
+func (s *TransactionService) GetByID(id int) (*models.Transaction, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *TransactionService) List(filter models.TransactionFilter) (*models.TransactionList, error) {
+	transactions, total, err := s.repo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TransactionList{
+		Data:       transactions,
+		Pagination: models.NewPagination(filter.Page, filter.Limit, total),
+	}, nil
 }
 
-func NewTransactionService(repo *repositories.TransactionRepository) *TransactionService {
-	return &TransactionService{repo: repo}
+func (s *TransactionService) Void(transactionID int, reason string) (*models.Refund, error) {
+	return s.repo.VoidTransaction(transactionID, reason)
 }
 
-func (s *TransactionService) Checkout(items []models.CheckoutItem) (*models.Transaction, error) {
-	return s.repo.CreateTransaction(items)
+func (s *TransactionService) Refund(transactionID int, req models.RefundRequest) (*models.Refund, error) {
+	return s.repo.RefundTransaction(transactionID, req)
 }
 
-func (s *TransactionService) GetSalesSummaryToday() (*models.SalesSummary, error) {
-	return s.repo.GetSalesSummaryToday()
+func (s *TransactionService) GetSalesSummaryToday(outletID int) (*models.SalesSummary, error) {
+	return s.repo.GetSalesSummaryToday(outletID)
 }
 
-func (s *TransactionService) GetSalesSummaryByDateRange(startDate, endDate string) (*models.SalesSummary, error) {
-	return s.repo.GetSalesSummaryByDateRange(startDate, endDate)
+func (s *TransactionService) GetSalesSummaryByDateRange(startDate, endDate string, outletID int) (*models.SalesSummary, error) {
+	return s.repo.GetSalesSummaryByDateRange(startDate, endDate, outletID)
 }
\ No newline at end of file
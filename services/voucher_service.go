@@ -0,0 +1,45 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type VoucherService struct {
+	repo *repositories.VoucherRepository
+}
+
+func NewVoucherService(repo *repositories.VoucherRepository) *VoucherService {
+	return &VoucherService{repo: repo}
+}
+
+func (s *VoucherService) GetAll(includeArchived bool) ([]models.Voucher, error) {
+	return s.repo.GetAll(includeArchived)
+}
+
+func (s *VoucherService) Create(data *models.Voucher) error {
+	return s.repo.Create(data)
+}
+
+func (s *VoucherService) GetByID(id int) (*models.Voucher, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *VoucherService) Update(voucher *models.Voucher) error {
+	return s.repo.Update(voucher)
+}
+
+func (s *VoucherService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+func (s *VoucherService) Restore(id int) (*models.Voucher, error) {
+	if err := s.repo.Restore(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *VoucherService) GetUsageReport(startDate, endDate string, outletID int) (*models.VoucherUsageReport, error) {
+	return s.repo.GetUsageReport(startDate, endDate, outletID)
+}